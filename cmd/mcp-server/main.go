@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"mcp-gemini-go/internal/mcp"
+
+	"github.com/joho/godotenv"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Servidor MCP via stdio para hosts como Claude Desktop e IDEs. O stdout é
+// reservado ao protocolo, então todo log vai para o stderr.
+func main() {
+	log.SetOutput(os.Stderr)
+
+	if err := godotenv.Load(".env"); err != nil {
+		log.Printf("Aviso: arquivo .env não encontrado, usando variáveis de ambiente: %v", err)
+	}
+
+	mcpServer := mcp.NewServer()
+	if err := mcpServer.Connect(); err != nil {
+		log.Fatalf("Erro ao conectar ao banco: %v", err)
+	}
+	defer mcpServer.Close()
+
+	if err := mcpServer.Initialize(); err != nil {
+		log.Fatalf("Erro ao inicializar servidor MCP: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	stdioServer := server.NewStdioServer(mcpServer.GetMCPServer())
+	stdioServer.SetErrorLogger(log.New(os.Stderr, "mcp-stdio: ", log.LstdFlags))
+
+	log.Println("🚀 Servidor MCP ouvindo via stdio")
+	if err := stdioServer.Listen(ctx, os.Stdin, os.Stdout); err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("Erro no servidor MCP: %v", err)
+	}
+	log.Println("👋 Servidor MCP encerrado")
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
 
+	"mcp-gemini-go/internal/mcp"
 	"mcp-gemini-go/internal/web/handlers"
 	"mcp-gemini-go/internal/web/services"
 )
@@ -15,13 +17,27 @@ func main() {
 	}
 	defer webService.Close()
 
-	chatHandler := handlers.NewChatHandler(webService.MCPClient, webService.Tools)
+	chatHandler := handlers.NewChatHandler(webService.MCPClient, webService.Tools, webService.Orchestrator)
+	conversationHandler := handlers.NewConversationHandler(webService.History)
 	staticHandler := handlers.NewStaticHandler("internal/web/html/static")
 
 	http.HandleFunc("/", chatHandler.HandleHome)
 	http.HandleFunc("/chat", chatHandler.HandleChat)
+	http.HandleFunc("/chat/stream", chatHandler.HandleChatStream)
+	http.HandleFunc("GET /conversations", conversationHandler.HandleList)
+	http.HandleFunc("GET /conversations/{id}", conversationHandler.HandleGet)
+	http.HandleFunc("DELETE /conversations/{id}", conversationHandler.HandleDelete)
 	http.HandleFunc("/static/", staticHandler.ServeFiles)
 
+	if tokens := mcp.ParseClientTokens(os.Getenv("MCP_HTTP_TOKENS")); len(tokens) > 0 {
+		mcpHandler := webService.MCPServer.HTTPHandler("/mcp", tokens)
+		http.Handle("/mcp", mcpHandler)
+		http.Handle("/mcp/", mcpHandler)
+		log.Printf("🔌 Ferramentas MCP disponíveis via HTTP em /mcp (%d clientes)", len(tokens))
+	} else {
+		log.Printf("Aviso: MCP_HTTP_TOKENS não definido, transporte HTTP do MCP desativado")
+	}
+
 	port := "80"
 	log.Printf("🚀 Servidor web iniciado em http://localhost:%s", port)
 
@@ -0,0 +1,124 @@
+package conversation
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxStoredMessages limita as mensagens guardadas por conversa; as mais
+// antigas são descartadas.
+const maxStoredMessages = 200
+
+// MemoryStore guarda as conversas em memória. Conversas sem atividade há mais
+// de maxAge são descartadas e, acima de maxConversations, as menos recentes
+// saem primeiro. Limites zerados desativam o descarte correspondente.
+type MemoryStore struct {
+	mu               sync.RWMutex
+	conversations    map[string]*Conversation
+	maxConversations int
+	maxAge           time.Duration
+}
+
+func NewMemoryStore(maxConversations int, maxAge time.Duration) *MemoryStore {
+	return &MemoryStore{
+		conversations:    make(map[string]*Conversation),
+		maxConversations: maxConversations,
+		maxAge:           maxAge,
+	}
+}
+
+func (s *MemoryStore) Append(ctx context.Context, session, id string, messages ...Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	conversation, exists := s.conversations[id]
+	if exists && conversation.Session != session {
+		return ErrForbidden
+	}
+	if !exists {
+		s.evict(now)
+		conversation = &Conversation{ID: id, Session: session, CreatedAt: now}
+		s.conversations[id] = conversation
+	}
+
+	for _, message := range messages {
+		if message.CreatedAt.IsZero() {
+			message.CreatedAt = now
+		}
+		conversation.Messages = append(conversation.Messages, message)
+	}
+	if excess := len(conversation.Messages) - maxStoredMessages; excess > 0 {
+		conversation.Messages = append([]Message(nil), conversation.Messages[excess:]...)
+	}
+	conversation.UpdatedAt = now
+	return nil
+}
+
+// evict remove as conversas expiradas e abre espaço para mais uma.
+func (s *MemoryStore) evict(now time.Time) {
+	if s.maxAge > 0 {
+		cutoff := now.Add(-s.maxAge)
+		for id, conversation := range s.conversations {
+			if conversation.UpdatedAt.Before(cutoff) {
+				delete(s.conversations, id)
+			}
+		}
+	}
+
+	for s.maxConversations > 0 && len(s.conversations) >= s.maxConversations {
+		var oldest *Conversation
+		for _, conversation := range s.conversations {
+			if oldest == nil || conversation.UpdatedAt.Before(oldest.UpdatedAt) {
+				oldest = conversation
+			}
+		}
+		delete(s.conversations, oldest.ID)
+	}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, session, id string) (*Conversation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conversation, exists := s.conversations[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	if conversation.Session != session {
+		return nil, ErrForbidden
+	}
+
+	copied := *conversation
+	copied.Messages = append([]Message(nil), conversation.Messages...)
+	return &copied, nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, session string) ([]Summary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var summaries []Summary
+	for _, conversation := range s.conversations {
+		if conversation.Session == session {
+			summaries = append(summaries, summarize(conversation))
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt)
+	})
+	return summaries, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, session, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if conversation, exists := s.conversations[id]; !exists || conversation.Session != session {
+		return ErrNotFound
+	}
+	delete(s.conversations, id)
+	return nil
+}
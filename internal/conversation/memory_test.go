@@ -0,0 +1,106 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreOwnership(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(0, 0)
+	if err := store.Append(ctx, "dono", "c1", Message{Role: RoleUser, Content: "oi"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	if conversation, err := store.Get(ctx, "dono", "c1"); err != nil || len(conversation.Messages) != 1 {
+		t.Fatalf("Get do dono = %+v, %v", conversation, err)
+	}
+	if _, err := store.Get(ctx, "outra", "c1"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Get de outra sessão = %v, esperado ErrForbidden", err)
+	}
+	if err := store.Append(ctx, "outra", "c1", Message{Role: RoleUser, Content: "invasão"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Append de outra sessão = %v, esperado ErrForbidden", err)
+	}
+	if summaries, _ := store.List(ctx, "outra"); len(summaries) != 0 {
+		t.Errorf("List de outra sessão = %+v, esperado vazio", summaries)
+	}
+
+	if err := store.Delete(ctx, "outra", "c1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete de outra sessão = %v, esperado ErrNotFound", err)
+	}
+	conversation, err := store.Get(ctx, "dono", "c1")
+	if err != nil || len(conversation.Messages) != 1 {
+		t.Fatalf("Delete de outra sessão não pode alterar a conversa: %+v, %v", conversation, err)
+	}
+
+	if err := store.Delete(ctx, "dono", "c1"); err != nil {
+		t.Fatalf("Delete do dono: %v", err)
+	}
+	if _, err := store.Get(ctx, "dono", "c1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get após Delete = %v, esperado ErrNotFound", err)
+	}
+}
+
+func TestMemoryStoreEvictsOldestConversations(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(3, 0)
+	for i := 1; i <= 3; i++ {
+		store.Append(ctx, "s", fmt.Sprintf("c%d", i), Message{Role: RoleUser, Content: "oi"})
+		store.conversations[fmt.Sprintf("c%d", i)].UpdatedAt = time.Now().Add(-time.Duration(10-i) * time.Minute)
+	}
+	// c1 é a menos recente, mas uma nova mensagem a torna a mais recente.
+	store.Append(ctx, "s", "c1", Message{Role: RoleUser, Content: "de novo"})
+
+	store.Append(ctx, "s", "c4", Message{Role: RoleUser, Content: "oi"})
+	if len(store.conversations) != 3 {
+		t.Fatalf("%d conversas guardadas, esperado 3", len(store.conversations))
+	}
+	if _, err := store.Get(ctx, "s", "c2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("c2 era a menos recente e deveria ter saído: %v", err)
+	}
+	for _, id := range []string{"c1", "c3", "c4"} {
+		if _, err := store.Get(ctx, "s", id); err != nil {
+			t.Errorf("%s deveria continuar guardada: %v", id, err)
+		}
+	}
+}
+
+func TestMemoryStoreEvictsIdleConversations(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(0, time.Hour)
+	store.Append(ctx, "s", "parada", Message{Role: RoleUser, Content: "oi"})
+	store.Append(ctx, "s", "ativa", Message{Role: RoleUser, Content: "oi"})
+	store.conversations["parada"].UpdatedAt = time.Now().Add(-2 * time.Hour)
+
+	// Conversas existentes não disparam o descarte; uma nova sim.
+	store.Append(ctx, "s", "ativa", Message{Role: RoleModel, Content: "olá"})
+	if _, err := store.Get(ctx, "s", "parada"); err != nil {
+		t.Fatalf("parada só sai quando uma conversa nova é criada: %v", err)
+	}
+	store.Append(ctx, "s", "nova", Message{Role: RoleUser, Content: "oi"})
+	if _, err := store.Get(ctx, "s", "parada"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("parada há mais de maxAge deveria ter saído: %v", err)
+	}
+	if _, err := store.Get(ctx, "s", "ativa"); err != nil {
+		t.Errorf("ativa deveria continuar guardada: %v", err)
+	}
+}
+
+func TestMemoryStoreCapsMessages(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(0, 0)
+	for i := 0; i < maxStoredMessages+10; i++ {
+		store.Append(ctx, "s", "c", Message{Role: RoleUser, Content: fmt.Sprint(i)})
+	}
+	conversation, err := store.Get(ctx, "s", "c")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(conversation.Messages) != maxStoredMessages || conversation.Messages[0].Content != "10" {
+		t.Errorf("%d mensagens começando por %q, esperado %d começando por \"10\"",
+			len(conversation.Messages), conversation.Messages[0].Content, maxStoredMessages)
+	}
+}
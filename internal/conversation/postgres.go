@@ -0,0 +1,222 @@
+package conversation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// schema é aplicado na criação do store porque o init.sql só roda na primeira
+// inicialização do volume do PostgreSQL.
+const schema = `
+	CREATE TABLE IF NOT EXISTS conversas (
+		id_conversa VARCHAR(64) PRIMARY KEY,
+		id_sessao VARCHAR(64) NOT NULL,
+		data_inclusao TIMESTAMP NOT NULL DEFAULT NOW(),
+		data_atualizacao TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS mensagens_conversa (
+		id_mensagem SERIAL PRIMARY KEY,
+		id_conversa VARCHAR(64) NOT NULL REFERENCES conversas(id_conversa) ON DELETE CASCADE,
+		papel VARCHAR(10) NOT NULL CHECK (papel IN ('user', 'model')),
+		conteudo TEXT NOT NULL,
+		origem VARCHAR(20),
+		data_inclusao TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_mensagens_conversa_id_conversa ON mensagens_conversa (id_conversa);
+	CREATE INDEX IF NOT EXISTS idx_conversas_id_sessao ON conversas (id_sessao);
+`
+
+// PostgresStore guarda as conversas no PostgreSQL com os mesmos limites do
+// MemoryStore: até maxStoredMessages mensagens por conversa e, a cada conversa
+// nova, o descarte das paradas há mais de maxAge e das menos recentes acima de
+// maxConversations. Limites zerados desativam o descarte correspondente.
+type PostgresStore struct {
+	db               *sql.DB
+	maxConversations int
+	maxAge           time.Duration
+}
+
+func NewPostgresStore(ctx context.Context, db *sql.DB, maxConversations int, maxAge time.Duration) (*PostgresStore, error) {
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, fmt.Errorf("erro ao criar tabelas de conversa: %w", err)
+	}
+	return &PostgresStore{db: db, maxConversations: maxConversations, maxAge: maxAge}, nil
+}
+
+func (s *PostgresStore) Append(ctx context.Context, session, id string, messages ...Message) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	// xmax = 0 só na linha recém-inserida; sem linha, a conversa é de outra sessão.
+	var created bool
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO conversas (id_conversa, id_sessao) VALUES ($1, $2)
+		ON CONFLICT (id_conversa) DO UPDATE SET data_atualizacao = NOW()
+		WHERE conversas.id_sessao = $2
+		RETURNING xmax = 0
+	`, id, session).Scan(&created)
+	if err == sql.ErrNoRows {
+		return ErrForbidden
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao salvar conversa: %w", err)
+	}
+	if created {
+		if err := s.evict(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	for _, message := range messages {
+		createdAt := message.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO mensagens_conversa (id_conversa, papel, conteudo, origem, data_inclusao)
+			VALUES ($1, $2, $3, $4, $5)
+		`, id, message.Role, message.Content, message.Source, createdAt)
+		if err != nil {
+			return fmt.Errorf("erro ao salvar mensagem: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM mensagens_conversa
+		WHERE id_conversa = $1 AND id_mensagem NOT IN (
+			SELECT id_mensagem FROM mensagens_conversa
+			WHERE id_conversa = $1
+			ORDER BY id_mensagem DESC
+			LIMIT $2
+		)
+	`, id, maxStoredMessages)
+	if err != nil {
+		return fmt.Errorf("erro ao descartar mensagens antigas: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// evict remove as conversas expiradas e as menos recentes além de
+// maxConversations, preservando a conversa kept recém-criada.
+func (s *PostgresStore) evict(ctx context.Context, tx *sql.Tx, kept string) error {
+	if s.maxAge > 0 {
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM conversas WHERE data_atualizacao < NOW() - make_interval(secs => $1) AND id_conversa <> $2
+		`, s.maxAge.Seconds(), kept)
+		if err != nil {
+			return fmt.Errorf("erro ao descartar conversas expiradas: %w", err)
+		}
+	}
+	if s.maxConversations > 0 {
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM conversas WHERE id_conversa IN (
+				SELECT id_conversa FROM conversas
+				WHERE id_conversa <> $2
+				ORDER BY data_atualizacao DESC
+				OFFSET $1
+			)
+		`, s.maxConversations-1, kept)
+		if err != nil {
+			return fmt.Errorf("erro ao descartar conversas antigas: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *PostgresStore) Get(ctx context.Context, session, id string) (*Conversation, error) {
+	conversation := &Conversation{ID: id}
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id_sessao, data_inclusao, data_atualizacao FROM conversas WHERE id_conversa = $1
+	`, id)
+	if err := row.Scan(&conversation.Session, &conversation.CreatedAt, &conversation.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar conversa: %w", err)
+	}
+	if conversation.Session != session {
+		return nil, ErrForbidden
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT papel, conteudo, origem, data_inclusao
+		FROM (
+			SELECT id_mensagem, papel, conteudo, COALESCE(origem, '') AS origem, data_inclusao
+			FROM mensagens_conversa
+			WHERE id_conversa = $1
+			ORDER BY id_mensagem DESC
+			LIMIT $2
+		) recentes
+		ORDER BY id_mensagem ASC
+	`, id, maxStoredMessages)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar mensagens: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var message Message
+		if err := rows.Scan(&message.Role, &message.Content, &message.Source, &message.CreatedAt); err != nil {
+			return nil, fmt.Errorf("erro ao escanear mensagem: %w", err)
+		}
+		conversation.Messages = append(conversation.Messages, message)
+	}
+
+	return conversation, rows.Err()
+}
+
+func (s *PostgresStore) List(ctx context.Context, session string) ([]Summary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			c.id_conversa,
+			c.data_inclusao,
+			c.data_atualizacao,
+			COUNT(m.id_mensagem),
+			COALESCE((
+				SELECT LEFT(primeira.conteudo, 60)
+				FROM mensagens_conversa primeira
+				WHERE primeira.id_conversa = c.id_conversa AND primeira.papel = 'user'
+				ORDER BY primeira.id_mensagem ASC
+				LIMIT 1
+			), '')
+		FROM conversas c
+		LEFT JOIN mensagens_conversa m ON m.id_conversa = c.id_conversa
+		WHERE c.id_sessao = $1
+		GROUP BY c.id_conversa
+		ORDER BY c.data_atualizacao DESC
+	`, session)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar conversas: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []Summary
+	for rows.Next() {
+		var summary Summary
+		if err := rows.Scan(&summary.ID, &summary.CreatedAt, &summary.UpdatedAt, &summary.MessageCount, &summary.Title); err != nil {
+			return nil, fmt.Errorf("erro ao escanear conversa: %w", err)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, rows.Err()
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, session, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM conversas WHERE id_conversa = $1 AND id_sessao = $2`, id, session)
+	if err != nil {
+		return fmt.Errorf("erro ao remover conversa: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
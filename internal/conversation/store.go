@@ -0,0 +1,120 @@
+package conversation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	RoleUser  = "user"
+	RoleModel = "model"
+)
+
+// MaxIDLength é o tamanho de id_conversa e id_sessao no PostgreSQL.
+const MaxIDLength = 64
+
+var (
+	ErrNotFound = errors.New("conversa não encontrada")
+	// ErrForbidden indica que a conversa existe, mas pertence a outra sessão.
+	ErrForbidden = errors.New("conversa pertence a outra sessão")
+)
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidID informa se id pode ser usado como sessão ou conversa: até
+// MaxIDLength letras, dígitos, '-' ou '_'.
+func ValidID(id string) bool {
+	return len(id) <= MaxIDLength && validID.MatchString(id)
+}
+
+type Message struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Source    string    `json:"source,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Conversation struct {
+	ID        string    `json:"id"`
+	Session   string    `json:"-"`
+	Messages  []Message `json:"messages"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Summary struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	MessageCount int       `json:"message_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Store guarda o histórico das conversas do chat. Cada conversa pertence à
+// sessão que a criou: Append e Get devolvem ErrForbidden para as outras
+// sessões, List só lista as conversas da sessão e Delete devolve ErrNotFound.
+type Store interface {
+	Append(ctx context.Context, session, id string, messages ...Message) error
+	Get(ctx context.Context, session, id string) (*Conversation, error)
+	List(ctx context.Context, session string) ([]Summary, error)
+	Delete(ctx context.Context, session, id string) error
+}
+
+// Limits controla quanto do histórico é reenviado ao modelo.
+type Limits struct {
+	MaxMessages int
+	MaxAge      time.Duration
+}
+
+// Recent devolve as mensagens dentro dos limites, começando sempre por uma
+// mensagem do usuário para manter a alternância de papéis exigida pelo Gemini.
+func (l Limits) Recent(messages []Message, now time.Time) []Message {
+	start := 0
+	if l.MaxAge > 0 {
+		cutoff := now.Add(-l.MaxAge)
+		for start < len(messages) && messages[start].CreatedAt.Before(cutoff) {
+			start++
+		}
+	}
+	if l.MaxMessages > 0 && len(messages)-start > l.MaxMessages {
+		start = len(messages) - l.MaxMessages
+	}
+	for start < len(messages) && messages[start].Role != RoleUser {
+		start++
+	}
+	return messages[start:]
+}
+
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+func summarize(conversation *Conversation) Summary {
+	title := ""
+	for _, message := range conversation.Messages {
+		if message.Role == RoleUser {
+			title = message.Content
+			break
+		}
+	}
+	if runes := []rune(title); len(runes) > 60 {
+		title = string(runes[:60]) + "…"
+	}
+
+	return Summary{
+		ID:           conversation.ID,
+		Title:        title,
+		MessageCount: len(conversation.Messages),
+		CreatedAt:    conversation.CreatedAt,
+		UpdatedAt:    conversation.UpdatedAt,
+	}
+}
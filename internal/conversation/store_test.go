@@ -0,0 +1,61 @@
+package conversation
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimitsRecent(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	at := func(hoursAgo int) time.Time { return now.Add(-time.Duration(hoursAgo) * time.Hour) }
+	messages := []Message{
+		{Role: RoleUser, Content: "u1", CreatedAt: at(30)},
+		{Role: RoleModel, Content: "m1", CreatedAt: at(30)},
+		{Role: RoleUser, Content: "u2", CreatedAt: at(5)},
+		{Role: RoleModel, Content: "m2", CreatedAt: at(5)},
+		{Role: RoleUser, Content: "u3", CreatedAt: at(1)},
+		{Role: RoleModel, Content: "m3", CreatedAt: at(1)},
+	}
+
+	tests := []struct {
+		name   string
+		limits Limits
+		want   string
+	}{
+		{"sem limites", Limits{}, "u1 m1 u2 m2 u3 m3"},
+		{"por idade", Limits{MaxAge: 24 * time.Hour}, "u2 m2 u3 m3"},
+		{"por quantidade", Limits{MaxMessages: 4}, "u2 m2 u3 m3"},
+		// Cortar em três começaria por uma resposta do modelo.
+		{"começa pelo usuário", Limits{MaxMessages: 3}, "u3 m3"},
+		{"idade e quantidade", Limits{MaxMessages: 10, MaxAge: 2 * time.Hour}, "u3 m3"},
+		{"tudo expirado", Limits{MaxAge: time.Minute}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, message := range tt.limits.Recent(messages, now) {
+				got = append(got, message.Content)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("Recent = %q, esperado %q", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidID(t *testing.T) {
+	for id, want := range map[string]bool{
+		NewID():                            true,
+		"sessao_1-a":                       true,
+		"":                                 false,
+		"com espaço":                       false,
+		"../etc":                           false,
+		strings.Repeat("a", MaxIDLength):   true,
+		strings.Repeat("a", MaxIDLength+1): false,
+	} {
+		if got := ValidID(id); got != want {
+			t.Errorf("ValidID(%q) = %v, esperado %v", id, got, want)
+		}
+	}
+}
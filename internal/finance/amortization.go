@@ -0,0 +1,163 @@
+// Package finance implementa os cálculos de financiamento usados pelas
+// ferramentas MCP e pelo chat. Taxas são frações (0.0149 = 1,49% ao mês).
+package finance
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// System é o sistema de amortização da dívida.
+type System string
+
+const (
+	// Price tem parcelas iguais; a amortização cresce ao longo do prazo.
+	Price System = "price"
+	// SAC tem amortização constante; as parcelas diminuem ao longo do prazo.
+	SAC System = "sac"
+)
+
+// Installment é uma linha da tabela de amortização.
+type Installment struct {
+	Number    int     `json:"parcela"`
+	Payment   float64 `json:"valor_parcela"`
+	Interest  float64 `json:"juros"`
+	Principal float64 `json:"amortizacao"`
+	Balance   float64 `json:"saldo_devedor"`
+}
+
+// Schedule é a tabela completa de um financiamento.
+type Schedule struct {
+	System        System        `json:"sistema"`
+	Principal     float64       `json:"valor_financiado"`
+	MonthlyRate   float64       `json:"taxa_mes"`
+	Installments  []Installment `json:"parcelas"`
+	TotalPaid     float64       `json:"valor_total"`
+	TotalInterest float64       `json:"total_juros"`
+}
+
+// ParseSystem aceita "price" ou "sac" sem diferenciar maiúsculas; vazio é Price.
+func ParseSystem(value string) (System, error) {
+	switch System(strings.ToLower(strings.TrimSpace(value))) {
+	case "", Price:
+		return Price, nil
+	case SAC:
+		return SAC, nil
+	}
+	return "", fmt.Errorf("sistema de amortização '%s' inválido (use price ou sac)", value)
+}
+
+// MonthlyFromAnnual converte uma taxa anual efetiva na taxa mensal equivalente
+// por capitalização composta: (1 + a)^(1/12) - 1.
+func MonthlyFromAnnual(annual float64) float64 {
+	return math.Pow(1+annual, 1.0/12) - 1
+}
+
+// AnnualFromMonthly converte uma taxa mensal na taxa anual efetiva equivalente.
+func AnnualFromMonthly(monthly float64) float64 {
+	return math.Pow(1+monthly, 12) - 1
+}
+
+// PricePayment é a parcela constante da Tabela Price.
+func PricePayment(principal, rate float64, months int) float64 {
+	if months <= 0 {
+		return 0
+	}
+	if rate == 0 {
+		return principal / float64(months)
+	}
+	factor := math.Pow(1+rate, float64(months))
+	return principal * rate * factor / (factor - 1)
+}
+
+// PricePrincipal é o valor que pode ser financiado com a parcela informada na
+// Tabela Price, o inverso de PricePayment.
+func PricePrincipal(payment, rate float64, months int) float64 {
+	if months <= 0 {
+		return 0
+	}
+	if rate == 0 {
+		return payment * float64(months)
+	}
+	return payment * (1 - math.Pow(1+rate, -float64(months))) / rate
+}
+
+// Amortize monta a tabela mês a mês. Os valores são arredondados para
+// centavos e a última parcela absorve a diferença, zerando o saldo.
+func Amortize(system System, principal, rate float64, months int) (*Schedule, error) {
+	if principal < 0 {
+		return nil, fmt.Errorf("valor financiado não pode ser negativo")
+	}
+	if months <= 0 {
+		return nil, fmt.Errorf("número de parcelas deve ser maior que zero")
+	}
+	if rate < 0 {
+		return nil, fmt.Errorf("taxa de juros não pode ser negativa")
+	}
+
+	schedule := &Schedule{
+		System:       system,
+		Principal:    Round(principal),
+		MonthlyRate:  rate,
+		Installments: make([]Installment, 0, months),
+	}
+
+	balance := Round(principal)
+	payment := Round(PricePayment(balance, rate, months))
+	amortization := Round(balance / float64(months))
+
+	for number := 1; number <= months; number++ {
+		interest := Round(balance * rate)
+
+		var principalPaid float64
+		switch system {
+		case SAC:
+			principalPaid = amortization
+		case Price:
+			principalPaid = payment - interest
+		default:
+			return nil, fmt.Errorf("sistema de amortização '%s' inválido", system)
+		}
+		if number == months || principalPaid > balance {
+			principalPaid = balance
+		}
+
+		balance = Round(balance - principalPaid)
+		installment := Installment{
+			Number:    number,
+			Payment:   Round(interest + principalPaid),
+			Interest:  interest,
+			Principal: Round(principalPaid),
+			Balance:   balance,
+		}
+		schedule.Installments = append(schedule.Installments, installment)
+		schedule.TotalPaid += installment.Payment
+		schedule.TotalInterest += installment.Interest
+	}
+
+	schedule.TotalPaid = Round(schedule.TotalPaid)
+	schedule.TotalInterest = Round(schedule.TotalInterest)
+	return schedule, nil
+}
+
+// First devolve a primeira parcela da tabela.
+func (s *Schedule) First() Installment {
+	if len(s.Installments) == 0 {
+		return Installment{}
+	}
+	return s.Installments[0]
+}
+
+// Last devolve a última parcela da tabela.
+func (s *Schedule) Last() Installment {
+	if len(s.Installments) == 0 {
+		return Installment{}
+	}
+	return s.Installments[len(s.Installments)-1]
+}
+
+// Round arredonda para centavos.
+func Round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
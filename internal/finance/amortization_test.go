@@ -0,0 +1,150 @@
+package finance
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAmortizePrice(t *testing.T) {
+	tests := []struct {
+		name          string
+		principal     float64
+		rate          float64
+		months        int
+		payment       float64
+		firstInterest float64
+	}{
+		// Parcelas iguais às de PMT(i, n, P) de uma calculadora financeira.
+		{"10 mil em 12x a 1%", 10000, 0.01, 12, 888.49, 100.00},
+		{"50 mil em 60x a 1,5%", 50000, 0.015, 60, 1269.67, 750.00},
+		{"30 mil em 24x a 1,99%", 30000, 0.0199, 24, 1584.34, 597.00},
+		{"sem juros", 12000, 0, 10, 1200.00, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Amortize(Price, tt.principal, tt.rate, tt.months)
+			if err != nil {
+				t.Fatalf("Amortize: %v", err)
+			}
+			if len(schedule.Installments) != tt.months {
+				t.Fatalf("%d parcelas, esperadas %d", len(schedule.Installments), tt.months)
+			}
+
+			first := schedule.First()
+			if first.Payment != tt.payment || first.Interest != tt.firstInterest {
+				t.Errorf("primeira parcela = %.2f (juros %.2f), esperado %.2f (juros %.2f)",
+					first.Payment, first.Interest, tt.payment, tt.firstInterest)
+			}
+			for _, installment := range schedule.Installments[:tt.months-1] {
+				if installment.Payment != tt.payment {
+					t.Errorf("parcela %d = %.2f, esperado %.2f", installment.Number, installment.Payment, tt.payment)
+				}
+			}
+			// A última parcela absorve o arredondamento, até meio centavo por mês.
+			if last := schedule.Last(); last.Balance != 0 || math.Abs(last.Payment-tt.payment) > 0.005*float64(tt.months) {
+				t.Errorf("última parcela = %.2f com saldo %.2f", last.Payment, last.Balance)
+			}
+			assertTotals(t, schedule)
+		})
+	}
+}
+
+func TestAmortizeSAC(t *testing.T) {
+	// R$ 120.000 em 12x a 1%: amortização de R$ 10.000 e juros sobre o saldo.
+	schedule, err := Amortize(SAC, 120000, 0.01, 12)
+	if err != nil {
+		t.Fatalf("Amortize: %v", err)
+	}
+
+	for i, installment := range schedule.Installments {
+		balanceBefore := 120000 - float64(i)*10000
+		want := Installment{
+			Number:    i + 1,
+			Payment:   10000 + balanceBefore*0.01,
+			Interest:  balanceBefore * 0.01,
+			Principal: 10000,
+			Balance:   balanceBefore - 10000,
+		}
+		if installment != want {
+			t.Errorf("parcela %d = %+v, esperado %+v", i+1, installment, want)
+		}
+	}
+	if schedule.TotalInterest != 7800 || schedule.TotalPaid != 127800 {
+		t.Errorf("totais = %.2f pagos e %.2f de juros, esperado 127800 e 7800", schedule.TotalPaid, schedule.TotalInterest)
+	}
+	assertTotals(t, schedule)
+}
+
+func assertTotals(t *testing.T, schedule *Schedule) {
+	t.Helper()
+	var paid, interest, principal float64
+	for _, installment := range schedule.Installments {
+		paid += installment.Payment
+		interest += installment.Interest
+		principal += installment.Principal
+	}
+	if math.Abs(Round(paid)-schedule.TotalPaid) > 0.001 || math.Abs(Round(interest)-schedule.TotalInterest) > 0.001 {
+		t.Errorf("totais %.2f/%.2f não batem com a soma das parcelas %.2f/%.2f",
+			schedule.TotalPaid, schedule.TotalInterest, paid, interest)
+	}
+	if math.Abs(Round(principal)-schedule.Principal) > 0.001 {
+		t.Errorf("amortização total %.2f difere do valor financiado %.2f", principal, schedule.Principal)
+	}
+}
+
+func TestAmortizeRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		system    System
+		principal float64
+		rate      float64
+		months    int
+	}{
+		{"prazo zero", Price, 10000, 0.01, 0},
+		{"valor negativo", Price, -1, 0.01, 12},
+		{"taxa negativa", SAC, 10000, -0.01, 12},
+		{"sistema desconhecido", System("alemao"), 10000, 0.01, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Amortize(tt.system, tt.principal, tt.rate, tt.months); err == nil {
+				t.Error("esperado erro")
+			}
+		})
+	}
+}
+
+func TestRateConversion(t *testing.T) {
+	tests := []struct {
+		annual  float64
+		monthly float64
+	}{
+		{0.126825, 0.01},
+		{0.425761, 0.03},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := MonthlyFromAnnual(tt.annual); math.Abs(got-tt.monthly) > 1e-6 {
+			t.Errorf("MonthlyFromAnnual(%v) = %v, esperado %v", tt.annual, got, tt.monthly)
+		}
+		if got := AnnualFromMonthly(tt.monthly); math.Abs(got-tt.annual) > 1e-6 {
+			t.Errorf("AnnualFromMonthly(%v) = %v, esperado %v", tt.monthly, got, tt.annual)
+		}
+	}
+
+	// Dividir a taxa anual por 12 superestima a taxa mensal equivalente.
+	if simple := 0.126825 / 12; MonthlyFromAnnual(0.126825) >= simple {
+		t.Errorf("a conversão composta deveria ficar abaixo de a/12 = %v", simple)
+	}
+}
+
+func TestParseSystem(t *testing.T) {
+	for value, want := range map[string]System{"": Price, "PRICE": Price, " sac ": SAC} {
+		if got, err := ParseSystem(value); err != nil || got != want {
+			t.Errorf("ParseSystem(%q) = %v, %v; esperado %v", value, got, err, want)
+		}
+	}
+	if _, err := ParseSystem("americano"); err == nil {
+		t.Error("ParseSystem deveria rejeitar sistemas desconhecidos")
+	}
+}
@@ -0,0 +1,174 @@
+package finance
+
+import (
+	"fmt"
+	"math"
+)
+
+// Alíquotas de IOF para pessoa física: a diária incide sobre cada amortização
+// pelo número de dias até o vencimento, limitado a 365 dias, e a adicional
+// incide uma vez sobre o valor financiado.
+const (
+	IOFDailyRate      = 0.000082
+	IOFMaxDays        = 365
+	IOFAdditionalRate = 0.0038
+	daysPerMonth      = 30
+)
+
+// Fees são os custos que, somados aos juros, formam o CET.
+type Fees struct {
+	TAC          float64 // tarifa de abertura de crédito/cadastro
+	Registration float64 // registro do gravame
+	// InsuranceRate é o seguro prestamista como fração do valor financiado.
+	InsuranceRate float64
+	// Financed indica se tarifas, seguro e IOF entram no valor financiado; caso
+	// contrário são pagos à vista na contratação.
+	Financed bool
+}
+
+// CET é o resultado do cálculo do Custo Efetivo Total.
+type CET struct {
+	Schedule      *Schedule `json:"-"`
+	Released      float64   `json:"valor_liberado"`
+	IOFDaily      float64   `json:"iof_diario"`
+	IOFAdditional float64   `json:"iof_adicional"`
+	IOF           float64   `json:"iof_total"`
+	TAC           float64   `json:"tac"`
+	Registration  float64   `json:"registro_gravame"`
+	Insurance     float64   `json:"seguro_prestamista"`
+	UpfrontCosts  float64   `json:"custos_a_vista"`
+	// Taxas em fração; as ferramentas as expõem em porcentagem.
+	MonthlyRate float64 `json:"-"`
+	AnnualRate  float64 `json:"-"`
+}
+
+// IOF calcula o IOF diário e adicional da tabela.
+func IOF(schedule *Schedule) (daily, additional float64) {
+	for _, installment := range schedule.Installments {
+		days := math.Min(float64(installment.Number*daysPerMonth), IOFMaxDays)
+		daily += installment.Principal * IOFDailyRate * days
+	}
+	return Round(daily), Round(schedule.Principal * IOFAdditionalRate)
+}
+
+// ComputeCET monta a tabela para liberar released ao cliente com a taxa
+// nominal rate e devolve o CET, a taxa interna de retorno do fluxo entre o
+// valor efetivamente recebido e as parcelas pagas.
+func ComputeCET(system System, released, rate float64, months int, fees Fees) (*CET, error) {
+	if released <= 0 {
+		return nil, fmt.Errorf("valor liberado deve ser maior que zero")
+	}
+
+	fixed := fees.TAC + fees.Registration
+	principal := released
+	if fees.Financed {
+		principal = released + fixed
+	}
+
+	var schedule *Schedule
+	var daily, additional, insurance float64
+	// Com custos financiados, o IOF e o seguro dependem do próprio valor
+	// financiado; a iteração converge em poucas voltas.
+	for i := 0; i < 50; i++ {
+		var err error
+		schedule, err = Amortize(system, principal, rate, months)
+		if err != nil {
+			return nil, err
+		}
+		daily, additional = IOF(schedule)
+		insurance = Round(principal * fees.InsuranceRate)
+		if !fees.Financed {
+			break
+		}
+
+		next := Round(released + fixed + insurance + daily + additional)
+		if math.Abs(next-principal) < 0.01 {
+			break
+		}
+		principal = next
+	}
+
+	result := &CET{
+		Schedule:      schedule,
+		Released:      Round(released),
+		IOFDaily:      daily,
+		IOFAdditional: additional,
+		IOF:           Round(daily + additional),
+		TAC:           fees.TAC,
+		Registration:  fees.Registration,
+		Insurance:     insurance,
+	}
+	if !fees.Financed {
+		result.UpfrontCosts = Round(fixed + insurance + daily + additional)
+	}
+
+	cashFlows := make([]float64, 0, months+1)
+	cashFlows = append(cashFlows, released-result.UpfrontCosts)
+	for _, installment := range schedule.Installments {
+		cashFlows = append(cashFlows, -installment.Payment)
+	}
+
+	monthly, err := IRR(cashFlows)
+	if err != nil {
+		return nil, err
+	}
+	result.MonthlyRate = monthly
+	result.AnnualRate = AnnualFromMonthly(monthly)
+	return result, nil
+}
+
+// IRR resolve a taxa por período que zera o valor presente do fluxo, com
+// Newton-Raphson e bissecção quando Newton não converge.
+func IRR(cashFlows []float64) (float64, error) {
+	if len(cashFlows) < 2 {
+		return 0, fmt.Errorf("fluxo de caixa precisa de ao menos dois valores")
+	}
+
+	npv := func(rate float64) (value, derivative float64) {
+		for t, flow := range cashFlows {
+			discount := math.Pow(1+rate, float64(t))
+			value += flow / discount
+			derivative -= float64(t) * flow / (discount * (1 + rate))
+		}
+		return value, derivative
+	}
+
+	rate := 0.01
+	for i := 0; i < 100; i++ {
+		value, derivative := npv(rate)
+		if math.Abs(value) < 1e-9 {
+			return rate, nil
+		}
+		if derivative == 0 {
+			break
+		}
+		next := rate - value/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < 1e-12 {
+			return next, nil
+		}
+		rate = next
+	}
+
+	low, high := -0.99, 10.0
+	lowValue, _ := npv(low)
+	highValue, _ := npv(high)
+	if lowValue*highValue > 0 {
+		return 0, fmt.Errorf("não foi possível calcular a taxa interna de retorno")
+	}
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		midValue, _ := npv(mid)
+		if math.Abs(midValue) < 1e-9 || high-low < 1e-12 {
+			return mid, nil
+		}
+		if (midValue > 0) == (lowValue > 0) {
+			low, lowValue = mid, midValue
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2, nil
+}
@@ -0,0 +1,155 @@
+package finance
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIOF(t *testing.T) {
+	tests := []struct {
+		name       string
+		system     System
+		principal  float64
+		rate       float64
+		months     int
+		daily      float64
+		additional float64
+	}{
+		// Até 12 meses nenhuma amortização passa de 360 dias.
+		{"10 mil em 12x Price", Price, 10000, 0.01, 12, 162.82, 38.00},
+		// Da 13ª parcela em diante o prazo fica limitado a 365 dias.
+		{"24 mil em 24x SAC", SAC, 24000, 0.01, 24, 551.04, 91.20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Amortize(tt.system, tt.principal, tt.rate, tt.months)
+			if err != nil {
+				t.Fatalf("Amortize: %v", err)
+			}
+			daily, additional := IOF(schedule)
+			if daily != tt.daily || additional != tt.additional {
+				t.Errorf("IOF = %.2f diário e %.2f adicional, esperado %.2f e %.2f", daily, additional, tt.daily, tt.additional)
+			}
+		})
+	}
+}
+
+func TestIOFCapsDays(t *testing.T) {
+	// Uma amortização de R$ 1.000 em 36x SAC: cada parcela após a 12ª paga o
+	// IOF de 365 dias, não de 30 dias por mês.
+	schedule, err := Amortize(SAC, 36000, 0, 36)
+	if err != nil {
+		t.Fatalf("Amortize: %v", err)
+	}
+	var capped, uncapped float64
+	for month := 1; month <= 36; month++ {
+		capped += 1000 * IOFDailyRate * math.Min(float64(month*30), IOFMaxDays)
+		uncapped += 1000 * IOFDailyRate * float64(month*30)
+	}
+	if daily, _ := IOF(schedule); daily != Round(capped) || daily >= Round(uncapped) {
+		t.Errorf("IOF diário = %.2f, esperado %.2f (sem limite seria %.2f)", daily, Round(capped), Round(uncapped))
+	}
+}
+
+func TestComputeCETFinancedFees(t *testing.T) {
+	fees := Fees{TAC: 800, Registration: 250, InsuranceRate: 0.03, Financed: true}
+	cet, err := ComputeCET(Price, 30000, 0.0199, 24, fees)
+	if err != nil {
+		t.Fatalf("ComputeCET: %v", err)
+	}
+
+	// O valor financiado cobre o liberado, as tarifas, o seguro e o IOF que
+	// incide sobre ele mesmo.
+	principal := cet.Schedule.Principal
+	if total := Round(cet.Released + cet.TAC + cet.Registration + cet.Insurance + cet.IOF); math.Abs(total-principal) >= 0.01 {
+		t.Errorf("valor financiado %.2f não é o ponto fixo dos custos (%.2f)", principal, total)
+	}
+	if principal != 32956.00 || cet.IOFDaily != 792.09 || cet.IOFAdditional != 125.23 || cet.Insurance != 988.68 {
+		t.Errorf("financiado %.2f, IOF %.2f + %.2f, seguro %.2f; esperado 32956.00, 792.09 + 125.23, 988.68",
+			principal, cet.IOFDaily, cet.IOFAdditional, cet.Insurance)
+	}
+	if payment := cet.Schedule.First().Payment; payment != 1740.45 {
+		t.Errorf("parcela = %.2f, esperado 1740.45", payment)
+	}
+	if cet.UpfrontCosts != 0 {
+		t.Errorf("custos financiados não são pagos à vista: %.2f", cet.UpfrontCosts)
+	}
+	assertRate(t, "CET mensal", cet.MonthlyRate, 0.028369)
+	assertRate(t, "CET anual", cet.AnnualRate, 0.398905)
+}
+
+func TestComputeCETUpfrontFees(t *testing.T) {
+	fees := Fees{TAC: 800, Registration: 250, InsuranceRate: 0.03}
+	cet, err := ComputeCET(Price, 30000, 0.0199, 24, fees)
+	if err != nil {
+		t.Fatalf("ComputeCET: %v", err)
+	}
+
+	if cet.Schedule.Principal != 30000 {
+		t.Errorf("sem custos financiados o valor financiado é o liberado, veio %.2f", cet.Schedule.Principal)
+	}
+	if cet.IOFDaily != 721.04 || cet.IOFAdditional != 114.00 || cet.UpfrontCosts != 2785.04 {
+		t.Errorf("IOF %.2f + %.2f e custos à vista %.2f; esperado 721.04 + 114.00 e 2785.04",
+			cet.IOFDaily, cet.IOFAdditional, cet.UpfrontCosts)
+	}
+	assertRate(t, "CET mensal", cet.MonthlyRate, 0.028687)
+	assertRate(t, "CET anual", cet.AnnualRate, 0.404110)
+}
+
+func TestComputeCETAboveNominalRate(t *testing.T) {
+	// Mesmo sem tarifas, o IOF faz o CET ficar acima da taxa contratada.
+	cet, err := ComputeCET(SAC, 50000, 0.015, 48, Fees{Financed: true})
+	if err != nil {
+		t.Fatalf("ComputeCET: %v", err)
+	}
+	if cet.MonthlyRate <= 0.015 || cet.IOF <= 0 {
+		t.Errorf("CET mensal %.6f com IOF %.2f deveria superar 1,5%%", cet.MonthlyRate, cet.IOF)
+	}
+	if _, err := ComputeCET(Price, 0, 0.01, 12, Fees{}); err == nil {
+		t.Error("valor liberado zero deveria ser rejeitado")
+	}
+}
+
+func TestIRR(t *testing.T) {
+	tests := []struct {
+		name      string
+		cashFlows []float64
+		rate      float64
+	}{
+		{"um período", []float64{-1000, 1100}, 0.10},
+		{"dois períodos", []float64{-1000, 0, 1210}, 0.10},
+		{"tabela Price a 1%", append([]float64{10000}, repeat(-888.487887, 12)...), 0.01},
+		{"taxa negativa", []float64{-1000, 900}, -0.10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := IRR(tt.cashFlows)
+			if err != nil {
+				t.Fatalf("IRR: %v", err)
+			}
+			assertRate(t, "TIR", rate, tt.rate)
+		})
+	}
+
+	if _, err := IRR([]float64{1000}); err == nil {
+		t.Error("fluxo com um único valor deveria ser rejeitado")
+	}
+	if _, err := IRR([]float64{1000, 1000}); err == nil {
+		t.Error("fluxo sem troca de sinal não tem taxa interna de retorno")
+	}
+}
+
+func repeat(value float64, n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = value
+	}
+	return values
+}
+
+func assertRate(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-6 {
+		t.Errorf("%s = %.6f, esperado %.6f", name, got, want)
+	}
+}
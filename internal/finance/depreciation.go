@@ -0,0 +1,78 @@
+package finance
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultRateSpread é a incerteza, em fração ao ano, usada na faixa de
+// confiança quando há menos de três pontos para estimar o erro do ajuste.
+const DefaultRateSpread = 0.03
+
+// confidenceZ dá uma faixa de aproximadamente 95% de confiança.
+const confidenceZ = 1.96
+
+// DepreciationPoint é uma observação do histórico: a idade do veículo em anos e
+// a fração do valor 0 km que ele ainda vale.
+type DepreciationPoint struct {
+	AgeYears  float64
+	Retention float64
+}
+
+// DepreciationFit é a curva exponencial ajustada, com as taxas em fração ao ano.
+// LowRate e HighRate delimitam a faixa de confiança da taxa.
+type DepreciationFit struct {
+	Rate     float64
+	LowRate  float64
+	HighRate float64
+	Points   int
+}
+
+// FitDepreciation ajusta valor(t) = valor_0km × (1 − taxa)^t por mínimos
+// quadrados sobre ln(retenção), com a curva passando por 1 na idade zero.
+// Taxas negativas (valorização) são tratadas como zero.
+func FitDepreciation(points []DepreciationPoint) (DepreciationFit, error) {
+	var sumAA, sumAY float64
+	var valid []DepreciationPoint
+	for _, p := range points {
+		if p.AgeYears <= 0 || p.Retention <= 0 {
+			continue
+		}
+		valid = append(valid, p)
+		sumAA += p.AgeYears * p.AgeYears
+		sumAY += p.AgeYears * math.Log(p.Retention)
+	}
+	if len(valid) == 0 {
+		return DepreciationFit{}, fmt.Errorf("sem pontos válidos no histórico de valorização")
+	}
+
+	slope := sumAY / sumAA
+	spread := DefaultRateSpread
+	lowSlope, highSlope := slope, slope
+	if len(valid) >= 3 {
+		var residuals float64
+		for _, p := range valid {
+			r := math.Log(p.Retention) - slope*p.AgeYears
+			residuals += r * r
+		}
+		stdErr := math.Sqrt(residuals / float64(len(valid)-1) / sumAA)
+		lowSlope, highSlope = slope+confidenceZ*stdErr, slope-confidenceZ*stdErr
+		spread = 0
+	}
+
+	rate := func(s float64) float64 { return math.Max(1-math.Exp(s), 0) }
+	return DepreciationFit{
+		Rate:     rate(slope),
+		LowRate:  math.Max(rate(lowSlope)-spread, 0),
+		HighRate: math.Min(rate(highSlope)+spread, 1),
+		Points:   len(valid),
+	}, nil
+}
+
+// Project devolve o valor esperado de value após years anos e a faixa de
+// confiança, do menor para o maior valor.
+func (f DepreciationFit) Project(value float64, years int) (expected, low, high float64) {
+	return DepreciatedValue(value, f.Rate, years),
+		DepreciatedValue(value, f.HighRate, years),
+		DepreciatedValue(value, f.LowRate, years)
+}
@@ -0,0 +1,75 @@
+package finance
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFitDepreciation(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []DepreciationPoint
+		fit    DepreciationFit
+	}{
+		{
+			// Com menos de três pontos a faixa usa a incerteza padrão.
+			name:   "dois pontos",
+			points: []DepreciationPoint{{1, 0.9}, {2, 0.81}},
+			fit:    DepreciationFit{Rate: 0.10, LowRate: 0.07, HighRate: 0.13, Points: 2},
+		},
+		{
+			// Pontos sobre a curva não têm resíduo: a faixa se fecha na taxa.
+			name:   "três pontos exatos",
+			points: []DepreciationPoint{{1, 0.9}, {2, 0.81}, {3, 0.729}},
+			fit:    DepreciationFit{Rate: 0.10, LowRate: 0.10, HighRate: 0.10, Points: 3},
+		},
+		{
+			name:   "valorização vira zero",
+			points: []DepreciationPoint{{1, 1.1}, {2, 1.2}},
+			fit:    DepreciationFit{Rate: 0, LowRate: 0, HighRate: DefaultRateSpread, Points: 2},
+		},
+		{
+			name:   "pontos inválidos são ignorados",
+			points: []DepreciationPoint{{0, 0.95}, {1, 0}, {-1, 0.9}, {1, 0.9}},
+			fit:    DepreciationFit{Rate: 0.10, LowRate: 0.07, HighRate: 0.13, Points: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fit, err := FitDepreciation(tt.points)
+			if err != nil {
+				t.Fatalf("FitDepreciation: %v", err)
+			}
+			if fit.Points != tt.fit.Points ||
+				math.Abs(fit.Rate-tt.fit.Rate) > 1e-9 ||
+				math.Abs(fit.LowRate-tt.fit.LowRate) > 1e-9 ||
+				math.Abs(fit.HighRate-tt.fit.HighRate) > 1e-9 {
+				t.Errorf("FitDepreciation = %+v, esperado %+v", fit, tt.fit)
+			}
+		})
+	}
+}
+
+func TestFitDepreciationNoisy(t *testing.T) {
+	// Com três pontos ou mais a faixa vem do erro do ajuste e envolve a taxa.
+	fit, err := FitDepreciation([]DepreciationPoint{{1, 0.88}, {2, 0.83}, {3, 0.70}, {4, 0.68}})
+	if err != nil {
+		t.Fatalf("FitDepreciation: %v", err)
+	}
+	if !(fit.LowRate < fit.Rate && fit.Rate < fit.HighRate) {
+		t.Errorf("faixa fora de ordem: %+v", fit)
+	}
+
+	expected, low, high := fit.Project(100000, 3)
+	if !(low < expected && expected < high) {
+		t.Errorf("Project = %.2f, %.2f, %.2f; esperado mínimo < esperado < máximo", expected, low, high)
+	}
+}
+
+func TestFitDepreciationWithoutPoints(t *testing.T) {
+	for _, points := range [][]DepreciationPoint{nil, {{0, 0.9}, {1, -0.5}}} {
+		if _, err := FitDepreciation(points); err == nil {
+			t.Errorf("FitDepreciation(%v) deveria falhar sem pontos válidos", points)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package finance
+
+import (
+	"fmt"
+	"math"
+)
+
+// Modalidades comparadas por CostSummary, com os mesmos nomes de
+// financiamentos.tipo_financiamento.
+const (
+	ModeCDC       = "CDC"
+	ModeLeasing   = "Leasing"
+	ModeConsorcio = "Consorcio"
+	ModeCash      = "A Vista"
+)
+
+// VRGMode define quando o valor residual garantido do leasing é pago.
+type VRGMode string
+
+const (
+	VRGFinal   VRGMode = "final"
+	VRGUpfront VRGMode = "antecipado"
+	VRGDiluted VRGMode = "diluido"
+)
+
+// ParseVRGMode aceita "final", "antecipado" ou "diluido"; vazio é VRGFinal.
+func ParseVRGMode(value string) (VRGMode, error) {
+	switch VRGMode(value) {
+	case "", VRGFinal:
+		return VRGFinal, nil
+	case VRGUpfront, VRGDiluted:
+		return VRGMode(value), nil
+	}
+	return "", fmt.Errorf("modo de VRG '%s' inválido (use final, antecipado ou diluido)", value)
+}
+
+// CostSummary resume qualquer modalidade no mesmo formato para comparação.
+// Valores em reais e taxas em porcentagem.
+type CostSummary struct {
+	Mode             string  `json:"modalidade"`
+	Provider         string  `json:"instituicao,omitempty"`
+	UpfrontPayment   float64 `json:"pagamento_inicial"`
+	Installments     int     `json:"numero_parcelas"`
+	FirstInstallment float64 `json:"valor_primeira_parcela"`
+	LastInstallment  float64 `json:"valor_ultima_parcela"`
+	FinalPayment     float64 `json:"pagamento_final"`
+	TotalPaid        float64 `json:"valor_total"`
+	TotalCost        float64 `json:"custo_total"`
+	// PresentCost é o custo trazido a valor presente por Discount: parcelas
+	// pagas e o veículo recebido descontados mês a mês, de modo que pagar
+	// antes ou receber depois (consórcio) pesa no custo.
+	PresentCost   float64  `json:"custo_valor_presente"`
+	CostPercent   float64  `json:"custo_percentual"`
+	DeliveryMonth int      `json:"mes_recebimento"`
+	EffectiveRate float64  `json:"custo_efetivo_mes,omitempty"`
+	Notes         []string `json:"observacoes,omitempty"`
+
+	flows []float64
+}
+
+// finish calcula total, custo e custo efetivo a partir do fluxo do cliente:
+// recebe o veículo (valor price) no mês de entrega e paga o restante. O custo
+// efetivo só é calculado quando o cliente recebe o veículo antes de pagar o
+// restante; no consórcio contemplado depois de pagar parcelas o fluxo deixa de
+// ser um empréstimo e a taxa interna de retorno não mede custo.
+func (c *CostSummary) finish(price float64, payments []float64) {
+	c.TotalPaid = Round(c.TotalPaid)
+	c.TotalCost = Round(c.TotalPaid - price)
+	if price > 0 {
+		c.CostPercent = Round(c.TotalCost / price * 100)
+	}
+
+	flows := make([]float64, len(payments))
+	for t, payment := range payments {
+		flows[t] = -payment
+	}
+	flows[c.DeliveryMonth] += price
+	c.flows = flows
+	c.PresentCost = c.TotalCost
+	if c.TotalCost <= 0 || !isLoan(flows) {
+		return
+	}
+	if rate, err := IRR(flows); err == nil {
+		c.EffectiveRate = Round(rate * 100)
+	}
+}
+
+// Discount recalcula PresentCost com a taxa mensal rate (fração), o custo de
+// oportunidade do cliente. Com rate zero, PresentCost é o custo nominal.
+func (c *CostSummary) Discount(rate float64) {
+	present := 0.0
+	for t, flow := range c.flows {
+		present -= flow / math.Pow(1+rate, float64(t))
+	}
+	c.PresentCost = Round(present)
+}
+
+// isLoan indica se o fluxo começa com entrada de recursos e depois só tem saídas.
+func isLoan(flows []float64) bool {
+	received := false
+	for _, flow := range flows {
+		switch {
+		case flow > 0 && received:
+			return false
+		case flow > 0:
+			received = true
+		case flow < 0 && !received:
+			return false
+		}
+	}
+	return received
+}
+
+// CDCSummary resume um financiamento CDC a partir da tabela de amortização.
+func CDCSummary(price, downPayment float64, schedule *Schedule) CostSummary {
+	summary := CostSummary{
+		Mode:             ModeCDC,
+		UpfrontPayment:   downPayment,
+		Installments:     len(schedule.Installments),
+		FirstInstallment: schedule.First().Payment,
+		LastInstallment:  schedule.Last().Payment,
+		TotalPaid:        downPayment + schedule.TotalPaid,
+	}
+
+	payments := []float64{downPayment}
+	for _, installment := range schedule.Installments {
+		payments = append(payments, installment.Payment)
+	}
+	summary.finish(price, payments)
+	return summary
+}
+
+// Leasing simula um arrendamento com VRG. As contraprestações amortizam o valor
+// arrendado à taxa mensal rate; o VRG é pago na entrada, diluído sem juros nas
+// parcelas ou no fim do contrato.
+func Leasing(price, downPayment, rate float64, months int, vrg float64, mode VRGMode) (CostSummary, error) {
+	if months <= 0 {
+		return CostSummary{}, fmt.Errorf("número de parcelas deve ser maior que zero")
+	}
+	if vrg < 0 || downPayment+vrg > price {
+		return CostSummary{}, fmt.Errorf("entrada e VRG não podem ultrapassar o valor do veículo")
+	}
+
+	summary := CostSummary{
+		Mode:           ModeLeasing,
+		UpfrontPayment: downPayment,
+		Installments:   months,
+		Notes:          []string{"leasing é isento de IOF; o veículo fica em nome do banco até a quitação do VRG"},
+	}
+
+	var installment float64
+	switch mode {
+	case VRGUpfront:
+		summary.UpfrontPayment += vrg
+		installment = PricePayment(price-downPayment-vrg, rate, months)
+	case VRGDiluted:
+		installment = PricePayment(price-downPayment-vrg, rate, months) + vrg/float64(months)
+	default:
+		// Parcela que, somada ao VRG no último mês, quita o valor arrendado.
+		present := price - downPayment - vrg/math.Pow(1+rate, float64(months))
+		installment = PricePayment(present, rate, months)
+		summary.FinalPayment = Round(vrg)
+	}
+	installment = Round(installment)
+
+	summary.FirstInstallment = installment
+	summary.LastInstallment = installment
+	summary.TotalPaid = summary.UpfrontPayment + installment*float64(months) + summary.FinalPayment
+
+	payments := make([]float64, months+1)
+	payments[0] = summary.UpfrontPayment
+	for t := 1; t <= months; t++ {
+		payments[t] = installment
+	}
+	payments[months] += summary.FinalPayment
+	summary.finish(price, payments)
+	return summary, nil
+}
+
+// Consorcio simula uma cota cuja carta de crédito completa o preço junto com a
+// entrada, paga na contemplação. Taxa de administração e fundo de reserva são
+// percentuais totais sobre a carta, divididos igualmente nas parcelas; o
+// veículo é recebido no mês de contemplação.
+func Consorcio(price, downPayment, adminFee, reserveFund float64, months, contemplationMonth int) (CostSummary, error) {
+	if months <= 0 {
+		return CostSummary{}, fmt.Errorf("número de parcelas deve ser maior que zero")
+	}
+	if contemplationMonth < 1 || contemplationMonth > months {
+		return CostSummary{}, fmt.Errorf("o mês de contemplação deve estar entre 1 e %d", months)
+	}
+	if adminFee < 0 || reserveFund < 0 {
+		return CostSummary{}, fmt.Errorf("taxa de administração e fundo de reserva não podem ser negativos")
+	}
+	if downPayment < 0 || downPayment >= price {
+		return CostSummary{}, fmt.Errorf("a entrada deve ficar entre zero e o valor do veículo")
+	}
+
+	credit := price - downPayment
+	installment := Round(credit * (1 + adminFee + reserveFund) / float64(months))
+	summary := CostSummary{
+		Mode:             ModeConsorcio,
+		Installments:     months,
+		FirstInstallment: installment,
+		LastInstallment:  installment,
+		TotalPaid:        installment*float64(months) + downPayment,
+		DeliveryMonth:    contemplationMonth,
+		Notes: []string{
+			fmt.Sprintf("cenário com contemplação no mês %d; a data real depende de sorteio ou lance", contemplationMonth),
+			"parcelas e carta de crédito são reajustadas pelo índice do grupo; o saldo do fundo de reserva é devolvido no encerramento",
+		},
+	}
+	if downPayment > 0 {
+		summary.Notes = append(summary.Notes, fmt.Sprintf(
+			"carta de crédito de R$ %.2f; a entrada de R$ %.2f completa o preço na contemplação", credit, downPayment))
+	}
+
+	payments := make([]float64, months+1)
+	for t := 1; t <= months; t++ {
+		payments[t] = installment
+	}
+	payments[contemplationMonth] += downPayment
+	summary.finish(price, payments)
+	return summary, nil
+}
+
+// Cash resume a compra à vista com desconto percentual (fração) e em reais.
+func Cash(price, discountRate, discountValue float64) CostSummary {
+	paid := math.Max(price*(1-discountRate)-discountValue, 0)
+	summary := CostSummary{
+		Mode:           ModeCash,
+		UpfrontPayment: Round(paid),
+		TotalPaid:      paid,
+	}
+	summary.finish(price, []float64{paid})
+	return summary
+}
@@ -0,0 +1,76 @@
+package finance
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDiscountAtContractRate(t *testing.T) {
+	// Descontado à própria taxa do contrato, o leasing sem VRG custa zero a
+	// valor presente, por maior que seja o custo nominal.
+	summary, err := Leasing(60000, 10000, 0.015, 48, 0, VRGFinal)
+	if err != nil {
+		t.Fatalf("Leasing: %v", err)
+	}
+	summary.Discount(0.015)
+	if summary.TotalCost <= 0 || math.Abs(summary.PresentCost) > 1 {
+		t.Errorf("custo nominal %.2f e a valor presente %.2f; esperado valor presente perto de zero",
+			summary.TotalCost, summary.PresentCost)
+	}
+
+	summary.Discount(0)
+	if summary.PresentCost != summary.TotalCost {
+		t.Errorf("sem desconto o custo a valor presente (%.2f) é o nominal (%.2f)", summary.PresentCost, summary.TotalCost)
+	}
+}
+
+func TestDiscountConsorcioDelivery(t *testing.T) {
+	// Consórcio sem taxas: nominalmente de graça, mas o cliente paga desde o
+	// primeiro mês e só recebe o veículo na contemplação.
+	early, err := Consorcio(60000, 0, 0, 0, 60, 1)
+	if err != nil {
+		t.Fatalf("Consorcio: %v", err)
+	}
+	late, err := Consorcio(60000, 0, 0, 0, 60, 60)
+	if err != nil {
+		t.Fatalf("Consorcio: %v", err)
+	}
+	early.Discount(0.01)
+	late.Discount(0.01)
+
+	if early.TotalCost != 0 || late.TotalCost != 0 {
+		t.Fatalf("custos nominais %.2f e %.2f, esperado zero", early.TotalCost, late.TotalCost)
+	}
+	if early.PresentCost >= 0 {
+		t.Errorf("contemplado no primeiro mês o cliente é financiado sem juros; custo %.2f deveria ser negativo", early.PresentCost)
+	}
+	if late.PresentCost <= early.PresentCost || late.PresentCost <= 0 {
+		t.Errorf("receber no último mês deveria custar mais: %.2f contra %.2f", late.PresentCost, early.PresentCost)
+	}
+
+	cash := Cash(60000, 0, 0)
+	cash.Discount(0.01)
+	if cash.PresentCost != 0 || late.PresentCost <= cash.PresentCost {
+		t.Errorf("à vista custa %.2f a valor presente e o consórcio tardio %.2f", cash.PresentCost, late.PresentCost)
+	}
+}
+
+func TestConsorcioDownPayment(t *testing.T) {
+	// Carta de R$ 40.000 com 10% de taxa em 50x e R$ 20.000 de entrada na
+	// contemplação do mês 10.
+	summary, err := Consorcio(60000, 20000, 0.10, 0, 50, 10)
+	if err != nil {
+		t.Fatalf("Consorcio: %v", err)
+	}
+	if summary.FirstInstallment != 880 || summary.TotalPaid != 64000 || summary.TotalCost != 4000 {
+		t.Errorf("parcela %.2f, total %.2f e custo %.2f; esperado 880, 64000 e 4000",
+			summary.FirstInstallment, summary.TotalPaid, summary.TotalCost)
+	}
+	if summary.flows[10] != 60000-880-20000 {
+		t.Errorf("no mês 10 o fluxo deveria somar o veículo, a parcela e a entrada: %.2f", summary.flows[10])
+	}
+
+	if _, err := Consorcio(60000, 60000, 0.10, 0, 50, 10); err == nil {
+		t.Error("entrada igual ao preço deveria ser recusada")
+	}
+}
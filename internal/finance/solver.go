@@ -0,0 +1,54 @@
+package finance
+
+import "fmt"
+
+// Prazos aceitos pelo solver, em meses.
+const (
+	MinTerm = 6
+	MaxTerm = 84
+)
+
+// RangeError explica por que não há solução dentro dos limites.
+type RangeError struct {
+	Reason string
+}
+
+func (e *RangeError) Error() string {
+	return e.Reason
+}
+
+// SolveTerm devolve o menor prazo entre MinTerm e MaxTerm cuja parcela na
+// Tabela Price não passa de payment.
+func SolveTerm(principal, rate, payment float64) (int, error) {
+	if payment <= 0 {
+		return 0, &RangeError{Reason: "a parcela deve ser maior que zero"}
+	}
+	for months := MinTerm; months <= MaxTerm; months++ {
+		if Round(PricePayment(principal, rate, months)) <= Round(payment) {
+			return months, nil
+		}
+	}
+	minimum := Round(PricePayment(principal, rate, MaxTerm))
+	return 0, &RangeError{Reason: fmt.Sprintf(
+		"nem em %d parcelas a prestação cabe em R$ %.2f; a menor parcela possível é R$ %.2f (aumente a entrada ou a parcela)",
+		MaxTerm, payment, minimum)}
+}
+
+// SolvePrincipal devolve o valor que pode ser financiado com a parcela e o prazo.
+func SolvePrincipal(payment, rate float64, months int) (float64, error) {
+	if err := CheckTerm(months); err != nil {
+		return 0, err
+	}
+	if payment <= 0 {
+		return 0, &RangeError{Reason: "a parcela deve ser maior que zero"}
+	}
+	return Round(PricePrincipal(payment, rate, months)), nil
+}
+
+// CheckTerm valida o prazo contra os limites do solver.
+func CheckTerm(months int) error {
+	if months < MinTerm || months > MaxTerm {
+		return &RangeError{Reason: fmt.Sprintf("o prazo deve ficar entre %d e %d parcelas", MinTerm, MaxTerm)}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package finance
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSolveTerm(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal float64
+		rate      float64
+		payment   float64
+		months    int
+	}{
+		{"parcela exata de 12x a 1%", 10000, 0.01, 888.49, 12},
+		{"um centavo a menos exige mais um mês", 10000, 0.01, 888.48, 13},
+		{"parcela folgada fica no prazo mínimo", 6000, 0.01, 5000, MinTerm},
+		{"prazo máximo sem juros", 8400, 0, 100, MaxTerm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			months, err := SolveTerm(tt.principal, tt.rate, tt.payment)
+			if err != nil || months != tt.months {
+				t.Errorf("SolveTerm = %d, %v; esperado %d", months, err, tt.months)
+			}
+		})
+	}
+}
+
+func TestSolveTermUnsolvable(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal float64
+		rate      float64
+		payment   float64
+	}{
+		{"um centavo abaixo do prazo máximo", 8400, 0, 99.99},
+		{"parcela não cobre os juros", 10000, 0.05, 400},
+		{"parcela zero", 10000, 0.01, 0},
+		{"parcela negativa", 10000, 0.01, -100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			months, err := SolveTerm(tt.principal, tt.rate, tt.payment)
+			var rangeErr *RangeError
+			if !errors.As(err, &rangeErr) || months != 0 {
+				t.Errorf("SolveTerm = %d, %v; esperado RangeError", months, err)
+			}
+		})
+	}
+}
+
+func TestSolvePrincipal(t *testing.T) {
+	tests := []struct {
+		name      string
+		payment   float64
+		rate      float64
+		months    int
+		principal float64
+	}{
+		{"12x de 888,49 a 1%", 888.49, 0.01, 12, 10000.02},
+		{"60x de 1.269,67 a 1,5%", 1269.67, 0.015, 60, 49999.95},
+		{"prazo mínimo", 1000, 0.01, MinTerm, 5795.48},
+		{"prazo máximo", 100, 0.01, MaxTerm, 5664.85},
+		{"sem juros", 500, 0, 24, 12000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := SolvePrincipal(tt.payment, tt.rate, tt.months)
+			if err != nil || principal != tt.principal {
+				t.Errorf("SolvePrincipal = %.2f, %v; esperado %.2f", principal, err, tt.principal)
+			}
+		})
+	}
+}
+
+func TestSolvePrincipalRejects(t *testing.T) {
+	for _, tt := range []struct {
+		payment float64
+		months  int
+	}{
+		{1000, MinTerm - 1},
+		{1000, MaxTerm + 1},
+		{0, 12},
+		{-1, 12},
+	} {
+		var rangeErr *RangeError
+		if _, err := SolvePrincipal(tt.payment, 0.01, tt.months); !errors.As(err, &rangeErr) {
+			t.Errorf("SolvePrincipal(%v, %d) = %v; esperado RangeError", tt.payment, tt.months, err)
+		}
+	}
+}
+
+func TestCheckTerm(t *testing.T) {
+	for months, valid := range map[int]bool{MinTerm - 1: false, MinTerm: true, 48: true, MaxTerm: true, MaxTerm + 1: false} {
+		if err := CheckTerm(months); (err == nil) != valid {
+			t.Errorf("CheckTerm(%d) = %v, esperado válido %v", months, err, valid)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package finance
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaintenanceItem é um serviço recorrente feito a cada IntervalKm quilômetros
+// ou IntervalMonths meses, o que vier primeiro. Intervalo zero é ignorado.
+type MaintenanceItem struct {
+	Name           string  `json:"servico"`
+	IntervalKm     int     `json:"intervalo_km,omitempty"`
+	IntervalMonths int     `json:"intervalo_meses,omitempty"`
+	Cost           float64 `json:"valor_medio"`
+}
+
+// MaintenanceState é o último serviço feito: a quilometragem e o mês relativo
+// ao início do plano (zero ou negativo).
+type MaintenanceState struct {
+	Km    float64
+	Month int
+}
+
+// MaintenanceEvent é um serviço previsto no plano. Month zero indica serviço
+// já vencido no início do plano.
+type MaintenanceEvent struct {
+	Item  int
+	Month int
+	Km    float64
+}
+
+// defaultState supõe o serviço em dia: feito no último múltiplo do intervalo
+// de quilometragem, no início do plano.
+func (m MaintenanceItem) defaultState(startKm float64) MaintenanceState {
+	state := MaintenanceState{Km: startKm}
+	if m.IntervalKm > 0 {
+		interval := float64(m.IntervalKm)
+		state.Km = math.Floor(startKm/interval) * interval
+	}
+	return state
+}
+
+func (m MaintenanceItem) due(last MaintenanceState, km float64, month int) bool {
+	return (m.IntervalMonths > 0 && month-last.Month >= m.IntervalMonths) ||
+		(m.IntervalKm > 0 && km-last.Km >= float64(m.IntervalKm))
+}
+
+// PlanMaintenance agenda os serviços mês a mês por months meses, rodando
+// annualKm por ano a partir de startKm. Cada serviço vence pelo intervalo de
+// quilometragem ou de meses, o que vier primeiro, contado do último serviço.
+// last[i] é o último serviço de items[i]; nil supõe o serviço em dia.
+func PlanMaintenance(items []MaintenanceItem, last []*MaintenanceState, startKm, annualKm float64, months int) []MaintenanceEvent {
+	states := make([]MaintenanceState, len(items))
+	for i, item := range items {
+		if i < len(last) && last[i] != nil {
+			states[i] = *last[i]
+		} else {
+			states[i] = item.defaultState(startKm)
+		}
+	}
+
+	var events []MaintenanceEvent
+	for month := 0; month <= months; month++ {
+		km := startKm + annualKm*float64(month)/12
+		for i, item := range items {
+			if item.IntervalKm <= 0 && item.IntervalMonths <= 0 {
+				continue
+			}
+			if item.due(states[i], km, month) {
+				events = append(events, MaintenanceEvent{Item: i, Month: month, Km: math.Round(km)})
+				states[i] = MaintenanceState{Km: km, Month: month}
+			}
+		}
+	}
+	return events
+}
+
+// OwnershipCosts são os custos do primeiro ano de uso. IPVA e seguro acompanham
+// o valor do veículo nos anos seguintes; licenciamento e combustível são fixos.
+type OwnershipCosts struct {
+	Price            float64
+	StartKm          float64
+	AnnualKm         float64
+	DepreciationRate float64 // fração ao ano
+	IPVA             float64
+	Licensing        float64
+	Insurance        float64
+	Fuel             float64
+	Maintenance      []MaintenanceItem
+}
+
+// TCOYear é o custo de um ano de posse.
+type TCOYear struct {
+	Year         int     `json:"ano"`
+	IPVA         float64 `json:"ipva"`
+	Licensing    float64 `json:"licenciamento"`
+	Insurance    float64 `json:"seguro"`
+	Maintenance  float64 `json:"manutencao"`
+	Fuel         float64 `json:"combustivel"`
+	Depreciation float64 `json:"depreciacao"`
+	Total        float64 `json:"total"`
+	Monthly      float64 `json:"custo_mensal"`
+	VehicleValue float64 `json:"valor_veiculo_fim_ano"`
+}
+
+// AnnualFuelCost é o gasto anual com combustível para annualKm quilômetros,
+// dos quais urbanShare (fração) na cidade, com consumos em km/l.
+func AnnualFuelCost(annualKm, urbanShare, urbanKmL, highwayKmL, fuelPrice float64) (float64, error) {
+	if urbanKmL <= 0 || highwayKmL <= 0 {
+		return 0, fmt.Errorf("consumo do veículo não cadastrado")
+	}
+	liters := annualKm*urbanShare/urbanKmL + annualKm*(1-urbanShare)/highwayKmL
+	return Round(liters * fuelPrice), nil
+}
+
+// DepreciatedValue é o valor após years anos de depreciação composta.
+func DepreciatedValue(price, rate float64, years int) float64 {
+	return Round(price * math.Pow(1-rate, float64(years)))
+}
+
+// ProjectTCO projeta o custo de posse ano a ano.
+func ProjectTCO(costs OwnershipCosts, years int) []TCOYear {
+	breakdown := make([]TCOYear, 0, years)
+	value := costs.Price
+
+	// Serviços vencidos no início do plano (mês zero) entram no primeiro ano.
+	maintenance := make([]float64, years)
+	for _, event := range PlanMaintenance(costs.Maintenance, nil, costs.StartKm, costs.AnnualKm, years*12) {
+		year := 0
+		if event.Month > 0 {
+			year = (event.Month - 1) / 12
+		}
+		maintenance[year] += costs.Maintenance[event.Item].Cost
+	}
+	for year := 1; year <= years; year++ {
+		// IPVA e seguro são proporcionais ao valor no início do ano.
+		scale := 1.0
+		if costs.Price > 0 {
+			scale = value / costs.Price
+		}
+		nextValue := DepreciatedValue(costs.Price, costs.DepreciationRate, year)
+
+		entry := TCOYear{
+			Year:         year,
+			IPVA:         Round(costs.IPVA * scale),
+			Licensing:    Round(costs.Licensing),
+			Insurance:    Round(costs.Insurance * scale),
+			Maintenance:  Round(maintenance[year-1]),
+			Fuel:         Round(costs.Fuel),
+			Depreciation: Round(value - nextValue),
+			VehicleValue: nextValue,
+		}
+		entry.Total = Round(entry.IPVA + entry.Licensing + entry.Insurance + entry.Maintenance + entry.Fuel + entry.Depreciation)
+		entry.Monthly = Round(entry.Total / 12)
+		breakdown = append(breakdown, entry)
+		value = nextValue
+	}
+	return breakdown
+}
@@ -0,0 +1,118 @@
+package finance
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanMaintenance(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     MaintenanceItem
+		last     *MaintenanceState
+		startKm  float64
+		annualKm float64
+		months   int
+		want     []MaintenanceEvent
+	}{
+		{
+			name:     "por meses",
+			item:     MaintenanceItem{IntervalMonths: 12},
+			annualKm: 12000, months: 36,
+			want: []MaintenanceEvent{{Month: 12, Km: 12000}, {Month: 24, Km: 24000}, {Month: 36, Km: 36000}},
+		},
+		{
+			name:     "por quilometragem",
+			item:     MaintenanceItem{IntervalKm: 10000},
+			annualKm: 12000, months: 24,
+			want: []MaintenanceEvent{{Month: 10, Km: 10000}, {Month: 20, Km: 20000}},
+		},
+		{
+			name:     "o que vier primeiro",
+			item:     MaintenanceItem{IntervalKm: 10000, IntervalMonths: 12},
+			annualKm: 6000, months: 24,
+			want: []MaintenanceEvent{{Month: 12, Km: 6000}, {Month: 24, Km: 12000}},
+		},
+		{
+			name:    "em dia supõe o último múltiplo do intervalo",
+			item:    MaintenanceItem{IntervalKm: 10000},
+			startKm: 25000, annualKm: 12000, months: 12,
+			want: []MaintenanceEvent{{Month: 5, Km: 30000}},
+		},
+		{
+			name:     "vencido por meses no início",
+			item:     MaintenanceItem{IntervalMonths: 12},
+			last:     &MaintenanceState{Month: -14},
+			annualKm: 12000, months: 12,
+			want: []MaintenanceEvent{{Month: 0, Km: 0}, {Month: 12, Km: 12000}},
+		},
+		{
+			name:    "vencido por quilometragem no início",
+			item:    MaintenanceItem{IntervalKm: 10000},
+			last:    &MaintenanceState{Km: 28000, Month: -3},
+			startKm: 40000, annualKm: 12000, months: 12,
+			want: []MaintenanceEvent{{Month: 0, Km: 40000}, {Month: 10, Km: 50000}},
+		},
+		{
+			name:     "sem intervalo nunca vence",
+			item:     MaintenanceItem{Cost: 500},
+			last:     &MaintenanceState{Month: -120},
+			annualKm: 12000, months: 60,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlanMaintenance([]MaintenanceItem{tt.item}, []*MaintenanceState{tt.last}, tt.startKm, tt.annualKm, tt.months)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PlanMaintenance = %+v, esperado %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectTCO(t *testing.T) {
+	// Revisão a cada 12 meses (meses 12, 24 e 36) e pneus a cada 20.000 km
+	// (mês 20): cada serviço cai no ano em que o mês termina.
+	costs := OwnershipCosts{
+		Price:            100000,
+		AnnualKm:         12000,
+		DepreciationRate: 0.10,
+		IPVA:             4000,
+		Licensing:        150,
+		Insurance:        3000,
+		Fuel:             6000,
+		Maintenance: []MaintenanceItem{
+			{Name: "revisão", IntervalMonths: 12, Cost: 1000},
+			{Name: "pneus", IntervalKm: 20000, Cost: 2000},
+			{Name: "sem intervalo", Cost: 999},
+		},
+	}
+	years := ProjectTCO(costs, 3)
+	if len(years) != 3 {
+		t.Fatalf("esperados 3 anos, vieram %d", len(years))
+	}
+
+	want := []TCOYear{
+		{Year: 1, IPVA: 4000, Licensing: 150, Insurance: 3000, Maintenance: 1000, Fuel: 6000,
+			Depreciation: 10000, Total: 24150, Monthly: 2012.5, VehicleValue: 90000},
+		{Year: 2, IPVA: 3600, Licensing: 150, Insurance: 2700, Maintenance: 3000, Fuel: 6000,
+			Depreciation: 9000, Total: 24450, Monthly: 2037.5, VehicleValue: 81000},
+		{Year: 3, IPVA: 3240, Licensing: 150, Insurance: 2430, Maintenance: 1000, Fuel: 6000,
+			Depreciation: 8100, Total: 20920, Monthly: 1743.33, VehicleValue: 72900},
+	}
+	for i := range want {
+		if years[i] != want[i] {
+			t.Errorf("ano %d = %+v, esperado %+v", i+1, years[i], want[i])
+		}
+	}
+}
+
+func TestProjectTCOZeroPrice(t *testing.T) {
+	// Sem preço cadastrado IPVA e seguro não escalam e não há depreciação.
+	years := ProjectTCO(OwnershipCosts{IPVA: 1000, Insurance: 2000, DepreciationRate: 0.1}, 2)
+	for _, year := range years {
+		if year.IPVA != 1000 || year.Insurance != 2000 || year.Depreciation != 0 {
+			t.Errorf("ano %d = %+v", year.Year, year)
+		}
+	}
+}
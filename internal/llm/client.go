@@ -2,22 +2,158 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
+const defaultMaxToolSteps = 5
+
+const systemPrompt = `🚗 CONSULTOR DE VENDAS AUTOMOTIVAS INTELIGENTE
+
+Você é um consultor especializado em vendas de veículos com acesso a uma base de dados completa da concessionária.
+
+REGRAS IMPORTANTES:
+1. ✅ SEMPRE use as ferramentas disponíveis para consultar dados reais
+2. ✅ Para perguntas sobre carros baratos/caros, use get_vehicles_available com filtros de preço
+3. ✅ Para simulações de financiamento, use calculate_financing
+4. ✅ Para melhores taxas, use get_best_financing
+5. ✅ Para comparar CDC, leasing, consórcio e compra à vista, use compare_payment_options e recomende a opção mais barata a valor presente (mais_barata), sem comparar modalidades pelo custo_total nominal, e lembre o mês de recebimento no consórcio
+6. ✅ Para promoções, use get_active_campaigns e informe o modelo nas simulações para aplicar a taxa especial
+7. ✅ Para "quanto vou gastar por mês de verdade?", use calculate_tco com o id_veiculo e pergunte o preço do combustível se não souber
+8. ✅ Para comparar veículos lado a lado, use compare_vehicles e apresente o resultado como tabela markdown, destacando o vencedor de cada linha
+9. ✅ Para "quanto fica o seguro?", use estimate_insurance com idade, gênero, cidade e tempo de CNH e avise quando a correspondencia não for exata
+10. ✅ Quando o cliente tiver um usado para dar na troca, use appraise_trade_in e passe o valor_liquido como trade_in_value nas simulações de financiamento
+11. ✅ Se um veículo listado tiver alerta_recall, avise o cliente; para detalhes use check_recalls
+12. ✅ Para IPVA, isenção de rodízio e incentivos de elétricos e híbridos, use get_tax_incentives com a cidade do cliente e considere o ipva_ajustado nos custos
+13. ✅ Para "esse carro desvaloriza muito?", use forecast_resale_value e informe o nivel_depreciacao, a faixa de valores e a liquidez de mercado
+14. ✅ Para revisões e custo de manutenção, use get_maintenance_plan e destaque os alertas de peças
+15. ✅ Para buscas por equipamentos, segurança, NCAP, combustível, categoria ou especificações, use search_vehicles; informe o total encontrado e ofereça a próxima página quando houver
+16. ❌ NUNCA invente dados - sempre consulte a base
+
+COMO RESPONDER A PERGUNTAS COMUNS:
+
+🔍 "carro barato" → Use get_vehicles_available com max_price baixo
+🔍 "carro mais caro" → Use get_vehicles_available sem filtro de preço e ordene por valor
+🔍 "simular parcelas de 60" → Use calculate_financing com installments=60
+🔍 "melhor financiamento" → Use get_best_financing
+🔍 "vale mais a pena leasing, consórcio ou à vista?" → Use compare_payment_options
+🔍 "comparar corolla e civic" → Use compare_vehicles
+🔍 "quanto custa o seguro do corolla?" → Use estimate_insurance
+🔍 "quanto vale meu civic 2019 na troca?" → Use appraise_trade_in
+🔍 "o civic 2022 tem recall?" → Use check_recalls
+🔍 "híbrido paga IPVA em São Paulo?" → Use get_tax_incentives
+🔍 "esse carro desvaloriza muito?" → Use forecast_resale_value
+🔍 "quanto gasto de revisão nos 3 primeiros anos?" → Use get_maintenance_plan
+🔍 "SUV com câmera de ré, ACC e 5 estrelas no Latin NCAP" → Use search_vehicles com category, safety e standard_features
+
+FORMATO DE RESPOSTA:
+💡 Baseado em nossa base de dados:
+[DADOS REAIS OBTIDOS DAS FERRAMENTAS]
+
+✨ Sempre inclua:
+- Preços dos veículos
+- Especificações importantes (consumo, potência, etc.)
+- Detalhes do financiamento (valor da parcela, taxa, banco)
+- Informações de IPVA e custos
+
+❓ Seja proativo oferecendo simulações e mais informações.
+
+LEMBRE-SE: Use as ferramentas para obter dados reais e atualizados!`
+
 type ChatResponse struct {
-	Content string `json:"content"`
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// ToolCall registra uma chamada de ferramenta feita pelo modelo durante o chat.
+type ToolCall struct {
+	Name  string                 `json:"name"`
+	Args  map[string]interface{} `json:"args"`
+	Error string                 `json:"error,omitempty"`
+}
+
+const (
+	EventToken     = "token"
+	EventToolStart = "tool_start"
+	EventToolEnd   = "tool_end"
+)
+
+// StreamEvent é emitido por StreamChat para cada token e chamada de ferramenta.
+type StreamEvent struct {
+	Type  string                 `json:"type"`
+	Text  string                 `json:"text,omitempty"`
+	Tool  string                 `json:"tool,omitempty"`
+	Args  map[string]interface{} `json:"args,omitempty"`
+	Error string                 `json:"error,omitempty"`
+}
+
+// Turn é uma mensagem anterior da conversa. Role é "user" ou "model".
+type Turn struct {
+	Role    string
+	Content string
+}
+
+// ToolExecutor executa as ferramentas pedidas pelo modelo (normalmente o mcp.Client).
+type ToolExecutor interface {
+	CallTool(ctx context.Context, name string, args map[string]interface{}) (string, error)
+}
+
+// Model abstrai o Gemini para que o loop de ferramentas rode contra um modelo falso.
+type Model interface {
+	StartChat(systemInstruction string, tools []*genai.Tool, history []*genai.Content) ChatSession
+}
+
+type ChatSession interface {
+	SendMessage(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error)
+	SendMessageStream(ctx context.Context, parts ...genai.Part) ResponseIterator
+}
+
+// ResponseIterator é satisfeito por *genai.GenerateContentResponseIterator e
+// termina com iterator.Done.
+type ResponseIterator interface {
+	Next() (*genai.GenerateContentResponse, error)
 }
 
 type Client struct {
-	model  *genai.GenerativeModel
+	MaxToolSteps int
+
+	model  Model
 	client *genai.Client
 }
 
+type geminiModel struct {
+	model *genai.GenerativeModel
+}
+
+func (g *geminiModel) StartChat(systemInstruction string, tools []*genai.Tool, history []*genai.Content) ChatSession {
+	model := *g.model
+	model.Tools = tools
+	if systemInstruction != "" {
+		model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(systemInstruction)}}
+	}
+	session := model.StartChat()
+	session.History = history
+	return &geminiSession{session: session}
+}
+
+type geminiSession struct {
+	session *genai.ChatSession
+}
+
+func (g *geminiSession) SendMessage(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+	return g.session.SendMessage(ctx, parts...)
+}
+
+func (g *geminiSession) SendMessageStream(ctx context.Context, parts ...genai.Part) ResponseIterator {
+	return g.session.SendMessageStream(ctx, parts...)
+}
+
 func NewClient(ctx context.Context, modelName string) (*Client, error) {
 	apiKey := os.Getenv("GOOGLE_API_KEY")
 	if apiKey == "" {
@@ -29,95 +165,317 @@ func NewClient(ctx context.Context, modelName string) (*Client, error) {
 		return nil, fmt.Errorf("erro ao criar cliente: %v", err)
 	}
 
-	model := client.GenerativeModel(modelName)
-
 	return &Client{
-		model:  model,
-		client: client,
+		MaxToolSteps: defaultMaxToolSteps,
+		model:        &geminiModel{model: client.GenerativeModel(modelName)},
+		client:       client,
 	}, nil
 }
 
+func NewClientWithModel(model Model) *Client {
+	return &Client{
+		MaxToolSteps: defaultMaxToolSteps,
+		model:        model,
+	}
+}
+
 func (c *Client) GenerateText(ctx context.Context, prompt string) (string, error) {
-	response, err := c.model.GenerateContent(ctx, genai.Text(prompt))
+	response, err := c.model.StartChat("", nil, nil).SendMessage(ctx, genai.Text(prompt))
 	if err != nil {
 		return "", fmt.Errorf("erro ao gerar conteúdo: %v", err)
 	}
 
-	if len(response.Candidates) == 0 {
-		return "", fmt.Errorf("nenhuma resposta gerada")
+	candidate, err := firstCandidate(response)
+	if err != nil {
+		return "", err
 	}
 
-	candidate := response.Candidates[0]
-	if len(candidate.Content.Parts) == 0 {
-		return "", fmt.Errorf("resposta vazia")
+	return candidateText(candidate)
+}
+
+// CompleteChat conversa com o modelo executando as chamadas de função que ele
+// pedir até obter uma resposta em texto ou atingir MaxToolSteps rodadas.
+// history traz os turnos anteriores da conversa, do mais antigo ao mais recente.
+func (c *Client) CompleteChat(ctx context.Context, history []Turn, message string, tools []map[string]interface{}, executor ToolExecutor) (*ChatResponse, error) {
+	return c.run(ctx, history, message, tools, executor, sendMessage, nil)
+}
+
+// StreamChat funciona como CompleteChat, mas envia os tokens do modelo e o
+// início e fim de cada chamada de ferramenta para emit à medida que acontecem.
+func (c *Client) StreamChat(ctx context.Context, history []Turn, message string, tools []map[string]interface{}, executor ToolExecutor, emit func(StreamEvent)) (*ChatResponse, error) {
+	return c.run(ctx, history, message, tools, executor, streamMessage(emit), emit)
+}
+
+// turnSender envia uma rodada ao modelo e devolve o texto e as chamadas de função da resposta.
+type turnSender func(ctx context.Context, session ChatSession, parts []genai.Part) (string, []genai.FunctionCall, error)
+
+func (c *Client) run(ctx context.Context, history []Turn, message string, tools []map[string]interface{}, executor ToolExecutor, send turnSender, emit func(StreamEvent)) (*ChatResponse, error) {
+	var genaiTools []*genai.Tool
+	if declarations := toolDeclarations(tools); len(declarations) > 0 {
+		genaiTools = []*genai.Tool{{FunctionDeclarations: declarations}}
 	}
 
-	if textPart, ok := candidate.Content.Parts[0].(genai.Text); ok {
-		return string(textPart), nil
+	notify := func(event StreamEvent) {
+		if emit != nil {
+			emit(event)
+		}
 	}
 
-	return "", fmt.Errorf("tipo de resposta não suportado")
-}
+	session := c.model.StartChat(systemPrompt, genaiTools, historyContents(history))
+	parts := []genai.Part{genai.Text(message)}
+	var toolCalls []ToolCall
 
-func (c *Client) CompleteChat(ctx context.Context, message string, tools []map[string]interface{}) (*ChatResponse, error) {
-	systemPrompt := `🚗 CONSULTOR DE VENDAS AUTOMOTIVAS INTELIGENTE
+	for step := 0; ; step++ {
+		text, calls, err := send(ctx, session, parts)
+		if err != nil {
+			return nil, err
+		}
 
-Você é um consultor especializado em vendas de veículos com acesso a uma base de dados completa da concessionária.
+		if len(calls) == 0 {
+			return &ChatResponse{Content: text, ToolCalls: toolCalls}, nil
+		}
 
-REGRAS IMPORTANTES:
-1. ✅ SEMPRE use as ferramentas disponíveis para consultar dados reais
-2. ✅ Para perguntas sobre carros baratos/caros, use get_vehicles_available com filtros de preço
-3. ✅ Para simulações de financiamento, use calculate_financing
-4. ✅ Para melhores taxas, use get_best_financing
-5. ❌ NUNCA invente dados - sempre consulte a base
+		if step >= c.MaxToolSteps {
+			return nil, fmt.Errorf("limite de %d rodadas de ferramentas atingido", c.MaxToolSteps)
+		}
+		if executor == nil {
+			return nil, fmt.Errorf("modelo pediu a ferramenta %s, mas nenhum executor foi configurado", calls[0].Name)
+		}
 
-FERRAMENTAS DISPONÍVEIS:
-- get_vehicles_available: busca veículos (filtros: max_price, brand, type)
-- get_best_financing: busca melhores opções de financiamento
-- calculate_financing: calcula parcelas específicas
+		// O histórico da sessão guarda a fatia enviada, então cada rodada usa uma nova.
+		parts = make([]genai.Part, 0, len(calls))
+		for _, call := range calls {
+			notify(StreamEvent{Type: EventToolStart, Tool: call.Name, Args: call.Args})
+			response, record := runTool(ctx, executor, call)
+			notify(StreamEvent{Type: EventToolEnd, Tool: call.Name, Error: record.Error})
 
-COMO RESPONDER A PERGUNTAS COMUNS:
+			toolCalls = append(toolCalls, record)
+			parts = append(parts, response)
+		}
+	}
+}
 
-🔍 "carro barato" → Use get_vehicles_available com max_price baixo
-🔍 "carro mais caro" → Use get_vehicles_available sem filtro de preço e ordene por valor
-🔍 "simular parcelas de 60" → Use calculate_financing com installments=60
-🔍 "melhor financiamento" → Use get_best_financing
+func sendMessage(ctx context.Context, session ChatSession, parts []genai.Part) (string, []genai.FunctionCall, error) {
+	response, err := session.SendMessage(ctx, parts...)
+	if err != nil {
+		return "", nil, fmt.Errorf("erro ao gerar conteúdo: %v", err)
+	}
 
-FORMATO DE RESPOSTA:
-💡 Baseado em nossa base de dados:
-[DADOS REAIS OBTIDOS DAS FERRAMENTAS]
+	candidate, err := firstCandidate(response)
+	if err != nil {
+		return "", nil, err
+	}
 
-✨ Sempre inclua:
-- Preços dos veículos
-- Especificações importantes (consumo, potência, etc.)
-- Detalhes do financiamento (valor da parcela, taxa, banco)
-- Informações de IPVA e custos
+	if calls := candidate.FunctionCalls(); len(calls) > 0 {
+		return "", calls, nil
+	}
 
-❓ Seja proativo oferecendo simulações e mais informações.
+	text, err := candidateText(candidate)
+	return text, nil, err
+}
 
-LEMBRE-SE: Use as ferramentas para obter dados reais e atualizados!`
+func streamMessage(emit func(StreamEvent)) turnSender {
+	return func(ctx context.Context, session ChatSession, parts []genai.Part) (string, []genai.FunctionCall, error) {
+		responses := session.SendMessageStream(ctx, parts...)
 
-	if len(tools) > 0 {
-		systemPrompt += "\n\nFERRAMENTAS ATIVAS:"
-		for _, tool := range tools {
-			if name, ok := tool["name"].(string); ok {
-				systemPrompt += "\n- " + name
+		var text strings.Builder
+		var calls []genai.FunctionCall
+		for {
+			response, err := responses.Next()
+			if err == iterator.Done {
+				break
 			}
+			if err != nil {
+				return "", nil, fmt.Errorf("erro ao gerar conteúdo: %v", err)
+			}
+			if len(response.Candidates) == 0 || response.Candidates[0].Content == nil {
+				continue
+			}
+
+			for _, part := range response.Candidates[0].Content.Parts {
+				switch p := part.(type) {
+				case genai.Text:
+					text.WriteString(string(p))
+					if emit != nil {
+						emit(StreamEvent{Type: EventToken, Text: string(p)})
+					}
+				case genai.FunctionCall:
+					calls = append(calls, p)
+				}
+			}
+		}
+
+		if len(calls) == 0 && text.Len() == 0 {
+			return "", nil, fmt.Errorf("resposta vazia")
 		}
+		return text.String(), calls, nil
 	}
+}
 
-	fullPrompt := fmt.Sprintf("%s\n\nUsuário: %s", systemPrompt, message)
+func (c *Client) Close() error {
+	if c.client == nil {
+		return nil
+	}
+	return c.client.Close()
+}
 
-	text, err := c.GenerateText(ctx, fullPrompt)
+func historyContents(history []Turn) []*genai.Content {
+	contents := make([]*genai.Content, 0, len(history))
+	for _, turn := range history {
+		if turn.Content == "" {
+			continue
+		}
+		contents = append(contents, &genai.Content{
+			Role:  turn.Role,
+			Parts: []genai.Part{genai.Text(turn.Content)},
+		})
+	}
+	return contents
+}
+
+func runTool(ctx context.Context, executor ToolExecutor, call genai.FunctionCall) (genai.FunctionResponse, ToolCall) {
+	record := ToolCall{Name: call.Name, Args: call.Args}
+
+	result, err := executor.CallTool(ctx, call.Name, call.Args)
 	if err != nil {
-		return nil, err
+		record.Error = err.Error()
+		return genai.FunctionResponse{
+			Name:     call.Name,
+			Response: map[string]any{"error": err.Error()},
+		}, record
 	}
 
-	return &ChatResponse{
-		Content: text,
-	}, nil
+	return genai.FunctionResponse{
+		Name:     call.Name,
+		Response: toolResponse(result),
+	}, record
 }
 
-func (c *Client) Close() error {
-	return c.client.Close()
+// toolResponse converte o texto devolvido pela ferramenta no objeto exigido
+// pelo FunctionResponse, preservando o JSON quando possível.
+func toolResponse(result string) map[string]any {
+	var decoded any
+	if err := json.Unmarshal([]byte(result), &decoded); err != nil {
+		return map[string]any{"result": result}
+	}
+	if object, ok := decoded.(map[string]any); ok {
+		return object
+	}
+	return map[string]any{"result": decoded}
+}
+
+func firstCandidate(response *genai.GenerateContentResponse) (*genai.Candidate, error) {
+	if response == nil || len(response.Candidates) == 0 {
+		return nil, fmt.Errorf("nenhuma resposta gerada")
+	}
+
+	candidate := response.Candidates[0]
+	if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
+		return nil, fmt.Errorf("resposta vazia")
+	}
+
+	return candidate, nil
+}
+
+func candidateText(candidate *genai.Candidate) (string, error) {
+	var text strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if textPart, ok := part.(genai.Text); ok {
+			text.WriteString(string(textPart))
+		}
+	}
+
+	if text.Len() == 0 {
+		return "", fmt.Errorf("tipo de resposta não suportado")
+	}
+
+	return text.String(), nil
+}
+
+// toolDeclarations converte as ferramentas no formato de mcp.Client.FormatToolsForLLM
+// em declarações de função do Gemini.
+func toolDeclarations(tools []map[string]interface{}) []*genai.FunctionDeclaration {
+	declarations := make([]*genai.FunctionDeclaration, 0, len(tools))
+	for _, tool := range tools {
+		function, ok := tool["function"].(map[string]interface{})
+		if !ok {
+			function = tool
+		}
+
+		name, _ := function["name"].(string)
+		if name == "" {
+			continue
+		}
+
+		description, _ := function["description"].(string)
+		declaration := &genai.FunctionDeclaration{
+			Name:        name,
+			Description: description,
+		}
+
+		if parameters, ok := function["parameters"].(map[string]interface{}); ok {
+			if schema := schemaFromJSON(parameters); len(schema.Properties) > 0 {
+				declaration.Parameters = schema
+			}
+		}
+
+		declarations = append(declarations, declaration)
+	}
+	return declarations
+}
+
+func schemaFromJSON(definition map[string]interface{}) *genai.Schema {
+	schema := &genai.Schema{}
+	schema.Description, _ = definition["description"].(string)
+
+	switch definition["type"] {
+	case "string":
+		schema.Type = genai.TypeString
+	case "number":
+		schema.Type = genai.TypeNumber
+	case "integer":
+		schema.Type = genai.TypeInteger
+	case "boolean":
+		schema.Type = genai.TypeBoolean
+	case "array":
+		schema.Type = genai.TypeArray
+		if items, ok := definition["items"].(map[string]interface{}); ok {
+			schema.Items = schemaFromJSON(items)
+		}
+	default:
+		schema.Type = genai.TypeObject
+	}
+
+	if values := stringList(definition["enum"]); len(values) > 0 {
+		schema.Format = "enum"
+		schema.Enum = values
+	}
+
+	if properties, ok := definition["properties"].(map[string]interface{}); ok {
+		schema.Properties = make(map[string]*genai.Schema, len(properties))
+		for name, property := range properties {
+			if propertyDefinition, ok := property.(map[string]interface{}); ok {
+				schema.Properties[name] = schemaFromJSON(propertyDefinition)
+			}
+		}
+	}
+	schema.Required = stringList(definition["required"])
+
+	return schema
+}
+
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
 }
@@ -0,0 +1,225 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+)
+
+// fakeModel devolve sempre a mesma sessão roteirizada.
+type fakeModel struct {
+	session *fakeSession
+}
+
+func (m *fakeModel) StartChat(systemInstruction string, tools []*genai.Tool, history []*genai.Content) ChatSession {
+	m.session.tools = tools
+	m.session.history = history
+	return m.session
+}
+
+// fakeSession responde cada rodada com a próxima resposta do roteiro e guarda
+// as partes recebidas.
+type fakeSession struct {
+	responses []*genai.GenerateContentResponse
+	sent      [][]genai.Part
+	tools     []*genai.Tool
+	history   []*genai.Content
+}
+
+func (s *fakeSession) next() (*genai.GenerateContentResponse, error) {
+	if len(s.sent) > len(s.responses) {
+		return nil, errors.New("roteiro esgotado")
+	}
+	return s.responses[len(s.sent)-1], nil
+}
+
+func (s *fakeSession) SendMessage(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+	s.sent = append(s.sent, parts)
+	return s.next()
+}
+
+func (s *fakeSession) SendMessageStream(ctx context.Context, parts ...genai.Part) ResponseIterator {
+	s.sent = append(s.sent, parts)
+	response, err := s.next()
+	return &fakeIterator{response: response, err: err}
+}
+
+type fakeIterator struct {
+	response *genai.GenerateContentResponse
+	err      error
+	done     bool
+}
+
+func (it *fakeIterator) Next() (*genai.GenerateContentResponse, error) {
+	if it.err != nil {
+		return nil, it.err
+	}
+	if it.done {
+		return nil, iterator.Done
+	}
+	it.done = true
+	return it.response, nil
+}
+
+func reply(parts ...genai.Part) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{Content: &genai.Content{Role: "model", Parts: parts}}},
+	}
+}
+
+type fakeExecutor struct {
+	results map[string]string
+	err     error
+	calls   []string
+}
+
+func (e *fakeExecutor) CallTool(ctx context.Context, name string, args map[string]interface{}) (string, error) {
+	e.calls = append(e.calls, name)
+	if e.err != nil {
+		return "", e.err
+	}
+	return e.results[name], nil
+}
+
+var testTools = []map[string]interface{}{{
+	"type": "function",
+	"function": map[string]interface{}{
+		"name":        "calculate_financing",
+		"description": "Simula um financiamento",
+		"parameters": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"installments": map[string]interface{}{"type": "number"},
+			},
+		},
+	},
+}}
+
+func financingCall() genai.FunctionCall {
+	return genai.FunctionCall{Name: "calculate_financing", Args: map[string]any{"installments": float64(60)}}
+}
+
+func TestCompleteChatRunsToolsUntilText(t *testing.T) {
+	session := &fakeSession{responses: []*genai.GenerateContentResponse{
+		reply(financingCall()),
+		reply(genai.Text("A parcela fica em R$ 2.000,00.")),
+	}}
+	executor := &fakeExecutor{results: map[string]string{"calculate_financing": `{"parcela": 2000}`}}
+	client := NewClientWithModel(&fakeModel{session: session})
+
+	history := []Turn{{Role: "user", Content: "oi"}, {Role: "model", Content: "Olá!"}}
+	response, err := client.CompleteChat(context.Background(), history, "simular em 60x", testTools, executor)
+	if err != nil {
+		t.Fatalf("CompleteChat: %v", err)
+	}
+
+	if response.Content != "A parcela fica em R$ 2.000,00." {
+		t.Errorf("Content = %q", response.Content)
+	}
+	if len(response.ToolCalls) != 1 || response.ToolCalls[0].Name != "calculate_financing" || response.ToolCalls[0].Error != "" {
+		t.Errorf("ToolCalls = %+v", response.ToolCalls)
+	}
+	if len(session.history) != 2 {
+		t.Errorf("histórico enviado com %d turnos, esperado 2", len(session.history))
+	}
+	if len(session.tools) != 1 || len(session.tools[0].FunctionDeclarations) != 1 {
+		t.Fatalf("ferramentas declaradas = %+v", session.tools)
+	}
+
+	if len(session.sent) != 2 {
+		t.Fatalf("%d rodadas enviadas, esperadas 2", len(session.sent))
+	}
+	functionResponse, ok := session.sent[1][0].(genai.FunctionResponse)
+	if !ok {
+		t.Fatalf("segunda rodada enviou %T, esperado FunctionResponse", session.sent[1][0])
+	}
+	if functionResponse.Name != "calculate_financing" || functionResponse.Response["parcela"] != float64(2000) {
+		t.Errorf("FunctionResponse = %+v", functionResponse)
+	}
+}
+
+func TestCompleteChatStopsAtMaxToolSteps(t *testing.T) {
+	session := &fakeSession{responses: []*genai.GenerateContentResponse{
+		reply(financingCall()),
+		reply(financingCall()),
+		reply(financingCall()),
+	}}
+	executor := &fakeExecutor{results: map[string]string{"calculate_financing": `{}`}}
+	client := NewClientWithModel(&fakeModel{session: session})
+	client.MaxToolSteps = 2
+
+	_, err := client.CompleteChat(context.Background(), nil, "simular", testTools, executor)
+	if err == nil || !strings.Contains(err.Error(), "limite de 2 rodadas") {
+		t.Fatalf("erro = %v, esperado limite de rodadas", err)
+	}
+	if len(executor.calls) != 2 {
+		t.Errorf("ferramenta executada %d vezes, esperado 2", len(executor.calls))
+	}
+}
+
+func TestCompleteChatReportsToolErrorsToModel(t *testing.T) {
+	session := &fakeSession{responses: []*genai.GenerateContentResponse{
+		reply(financingCall()),
+		reply(genai.Text("Não consegui simular agora.")),
+	}}
+	executor := &fakeExecutor{err: errors.New("banco indisponível")}
+	client := NewClientWithModel(&fakeModel{session: session})
+
+	response, err := client.CompleteChat(context.Background(), nil, "simular", testTools, executor)
+	if err != nil {
+		t.Fatalf("CompleteChat: %v", err)
+	}
+	if len(response.ToolCalls) != 1 || response.ToolCalls[0].Error != "banco indisponível" {
+		t.Errorf("ToolCalls = %+v", response.ToolCalls)
+	}
+
+	functionResponse := session.sent[1][0].(genai.FunctionResponse)
+	if functionResponse.Response["error"] != "banco indisponível" {
+		t.Errorf("FunctionResponse = %+v, esperado o erro da ferramenta", functionResponse.Response)
+	}
+}
+
+func TestCompleteChatWithoutExecutor(t *testing.T) {
+	session := &fakeSession{responses: []*genai.GenerateContentResponse{reply(financingCall())}}
+	client := NewClientWithModel(&fakeModel{session: session})
+
+	_, err := client.CompleteChat(context.Background(), nil, "simular", testTools, nil)
+	if err == nil || !strings.Contains(err.Error(), "nenhum executor") {
+		t.Fatalf("erro = %v, esperado executor ausente", err)
+	}
+}
+
+func TestStreamChatEmitsTokensAndToolEvents(t *testing.T) {
+	session := &fakeSession{responses: []*genai.GenerateContentResponse{
+		reply(financingCall()),
+		reply(genai.Text("Parcela de R$ 2.000,00")),
+	}}
+	executor := &fakeExecutor{results: map[string]string{"calculate_financing": `[1, 2]`}}
+	client := NewClientWithModel(&fakeModel{session: session})
+
+	var events []string
+	response, err := client.StreamChat(context.Background(), nil, "simular", testTools, executor, func(event StreamEvent) {
+		events = append(events, event.Type)
+	})
+	if err != nil {
+		t.Fatalf("StreamChat: %v", err)
+	}
+	if response.Content != "Parcela de R$ 2.000,00" {
+		t.Errorf("Content = %q", response.Content)
+	}
+
+	want := []string{EventToolStart, EventToolEnd, EventToken}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Errorf("eventos = %v, esperado %v", events, want)
+	}
+
+	// Resultados que não são objetos JSON vão embrulhados em "result".
+	functionResponse := session.sent[1][0].(genai.FunctionResponse)
+	if _, ok := functionResponse.Response["result"].([]any); !ok {
+		t.Errorf("FunctionResponse = %+v, esperado result com a lista", functionResponse.Response)
+	}
+}
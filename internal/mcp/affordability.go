@@ -0,0 +1,348 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mcp-gemini-go/internal/finance"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// creditTier acrescenta Spread (pontos percentuais ao mês) à taxa do banco para
+// scores a partir de MinScore. Scores abaixo da menor faixa são recusados.
+type creditTier struct {
+	Name     string  `json:"faixa"`
+	MinScore int     `json:"score_minimo"`
+	Spread   float64 `json:"acrescimo_taxa_mes"`
+}
+
+const defaultCreditTiers = "800:0,650:0.25,500:0.6"
+
+// parseCreditTiers lê faixas no formato "score:acréscimo,..." e as nomeia
+// A, B, C... da melhor para a pior.
+func parseCreditTiers(spec string) ([]creditTier, error) {
+	var tiers []creditTier
+	for _, entry := range strings.Split(spec, ",") {
+		score, spread, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found {
+			return nil, fmt.Errorf("faixa de score '%s' inválida", entry)
+		}
+		minScore, err := strconv.Atoi(strings.TrimSpace(score))
+		if err != nil {
+			return nil, fmt.Errorf("score mínimo '%s' inválido", score)
+		}
+		spreadValue, err := strconv.ParseFloat(strings.TrimSpace(spread), 64)
+		if err != nil || spreadValue < 0 {
+			return nil, fmt.Errorf("acréscimo de taxa '%s' inválido", spread)
+		}
+		tiers = append(tiers, creditTier{MinScore: minScore, Spread: spreadValue})
+	}
+
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinScore > tiers[j].MinScore })
+	for i := range tiers {
+		tiers[i].Name = string(rune('A' + i))
+	}
+	return tiers, nil
+}
+
+func tierFor(tiers []creditTier, score int) (creditTier, bool) {
+	for _, tier := range tiers {
+		if score >= tier.MinScore {
+			return tier, true
+		}
+	}
+	return creditTier{}, false
+}
+
+type affordableVehicle struct {
+	Brand   string  `json:"marca"`
+	Model   string  `json:"modelo"`
+	Version string  `json:"versao"`
+	Price   float64 `json:"preco_venda"`
+	// PromotionalPrice e Campaign vêm de applyCampaignPrices; quando há
+	// campanha, a análise usa o preço promocional.
+	PromotionalPrice float64 `json:"preco_promocional,omitempty"`
+	Campaign         string  `json:"campanha,omitempty"`
+	Installment      float64 `json:"valor_parcela,omitempty"`
+	Installments     int     `json:"numero_parcelas,omitempty"`
+	Bank             string  `json:"banco,omitempty"`
+	Reason           string  `json:"motivo,omitempty"`
+}
+
+// price é o preço usado na análise: o promocional, quando houver.
+func (v affordableVehicle) price() float64 {
+	if v.PromotionalPrice > 0 {
+		return v.PromotionalPrice
+	}
+	return v.Price
+}
+
+type affordableOffer struct {
+	Bank            string  `json:"banco"`
+	Type            string  `json:"tipo"`
+	Installments    int     `json:"parcelas"`
+	MonthlyRate     float64 `json:"taxa_mes"`
+	MaxVehiclePrice float64 `json:"valor_maximo_veiculo,omitempty"`
+	Reason          string  `json:"motivo,omitempty"`
+}
+
+type affordabilityResult struct {
+	Tier              *creditTier         `json:"perfil_credito,omitempty"`
+	MaxIncomeRatio    float64             `json:"comprometimento_maximo"`
+	MaxInstallment    float64             `json:"parcela_maxima"`
+	DownPayment       float64             `json:"valor_entrada"`
+	TradeIn           float64             `json:"valor_troca,omitempty"`
+	Note              string              `json:"observacao,omitempty"`
+	VehicleLimitNote  string              `json:"aviso_limite_veiculos,omitempty"`
+	VehiclesAvailable int                 `json:"veiculos_disponiveis"`
+	VehiclesAnalyzed  int                 `json:"veiculos_analisados"`
+	Vehicles          []affordableVehicle `json:"veiculos_aprovados"`
+	ExcludedVehicles  []affordableVehicle `json:"veiculos_recusados"`
+	Offers            []affordableOffer   `json:"financiamentos_aprovados"`
+	ExcludedOffers    []affordableOffer   `json:"financiamentos_recusados"`
+}
+
+// CheckAffordability cruza renda e score com os veículos disponíveis e os
+// financiamentos aprovados. A renda e o score do cliente não são devolvidos,
+// apenas a parcela máxima e a faixa de crédito.
+func (s *Server) CheckAffordability(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	income := request.GetFloat("monthly_income", 0)
+	score := request.GetInt("credit_score", -1)
+
+	if customerID := request.GetInt("customer_id", 0); customerID > 0 {
+		var storedIncome sql.NullFloat64
+		var storedScore sql.NullInt64
+		err := s.DB.QueryRowContext(ctx,
+			"SELECT renda_mensal, score_credito FROM clientes WHERE id_clientes = $1", customerID,
+		).Scan(&storedIncome, &storedScore)
+		if err == sql.ErrNoRows {
+			return mcp.NewToolResultError(fmt.Sprintf("cliente %d não encontrado", customerID)), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("erro ao buscar cliente: %v", err)), nil
+		}
+		if !storedIncome.Valid {
+			return mcp.NewToolResultError(fmt.Sprintf("cliente %d não tem renda cadastrada; informe monthly_income", customerID)), nil
+		}
+		income = storedIncome.Float64
+		if storedScore.Valid {
+			score = int(storedScore.Int64)
+		}
+	}
+
+	if income <= 0 {
+		return mcp.NewToolResultError("informe customer_id ou monthly_income maior que zero"), nil
+	}
+
+	downPayment, tradeIn, errResult := entryFrom(request)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	result := affordabilityResult{
+		MaxIncomeRatio:   s.maxIncomeRatio * 100,
+		MaxInstallment:   finance.Round(income * s.maxIncomeRatio),
+		DownPayment:      downPayment,
+		TradeIn:          tradeIn,
+		Vehicles:         []affordableVehicle{},
+		ExcludedVehicles: []affordableVehicle{},
+		Offers:           []affordableOffer{},
+		ExcludedOffers:   []affordableOffer{},
+	}
+
+	var spread float64
+	rejection := ""
+	if score < 0 {
+		result.Note = "score não informado; taxas consideradas sem acréscimo por faixa"
+	} else if tier, ok := tierFor(s.creditTiers, score); ok {
+		result.Tier = &tier
+		spread = tier.Spread
+	} else {
+		rejection = fmt.Sprintf("score abaixo do mínimo de %d exigido para financiamento", s.creditTiers[len(s.creditTiers)-1].MinScore)
+		result.Note = rejection
+	}
+
+	offers, err := s.loadFinancingOffers(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("erro ao buscar financiamentos: %v", err)), nil
+	}
+	vehicles, available, err := s.loadVehiclePrices(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("erro ao buscar veículos: %v", err)), nil
+	}
+	result.VehiclesAvailable = available
+	result.VehiclesAnalyzed = len(vehicles)
+	if available > len(vehicles) {
+		result.VehicleLimitNote = fmt.Sprintf("foram analisados os %d veículos de menor preço entre os %d disponíveis", len(vehicles), available)
+	}
+
+	cheapest := 0.0
+	if len(vehicles) > 0 {
+		cheapest = vehicles[0].price()
+	}
+
+	for _, offer := range offers {
+		offer.MonthlyRate += spread
+		rate := offer.MonthlyRate / 100
+		maxPrice := finance.Round(downPayment + finance.PricePrincipal(result.MaxInstallment, rate, offer.Installments))
+
+		switch {
+		case rejection != "":
+			offer.Reason = rejection
+		case len(vehicles) > 0 && maxPrice < cheapest:
+			offer.Reason = fmt.Sprintf("em %d parcelas de até R$ %.2f o valor máximo (R$ %.2f) não cobre o veículo mais barato (R$ %.2f)",
+				offer.Installments, result.MaxInstallment, maxPrice, cheapest)
+		default:
+			offer.MaxVehiclePrice = maxPrice
+			result.Offers = append(result.Offers, offer)
+			continue
+		}
+		result.ExcludedOffers = append(result.ExcludedOffers, offer)
+	}
+
+	for _, vehicle := range vehicles {
+		financed := vehicle.price() - downPayment
+		if financed <= 0 {
+			result.Vehicles = append(result.Vehicles, vehicle)
+			continue
+		}
+
+		if rejection != "" {
+			vehicle.Reason = rejection
+			result.ExcludedVehicles = append(result.ExcludedVehicles, vehicle)
+			continue
+		}
+
+		// A melhor opção é a de menor parcela entre as ofertas.
+		var best *affordableOffer
+		bestInstallment := 0.0
+		for i := range offers {
+			offer := offers[i]
+			installment := finance.Round(finance.PricePayment(financed, (offer.MonthlyRate+spread)/100, offer.Installments))
+			if best == nil || installment < bestInstallment {
+				best, bestInstallment = &offers[i], installment
+			}
+		}
+
+		switch {
+		case best == nil:
+			vehicle.Reason = "nenhum financiamento aprovado disponível"
+		case bestInstallment > result.MaxInstallment:
+			vehicle.Reason = fmt.Sprintf("a menor parcela (R$ %.2f em %d vezes, %s) passa do teto de R$ %.2f (%.0f%% da renda)",
+				bestInstallment, best.Installments, best.Bank, result.MaxInstallment, result.MaxIncomeRatio)
+		default:
+			vehicle.Installment = bestInstallment
+			vehicle.Installments = best.Installments
+			vehicle.Bank = best.Bank
+			result.Vehicles = append(result.Vehicles, vehicle)
+			continue
+		}
+		result.ExcludedVehicles = append(result.ExcludedVehicles, vehicle)
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+// loadFinancingOffers busca as condições de CDC aprovadas, da menor para a
+// maior taxa. Leasing e consórcio têm custos que a parcela da tabela Price não
+// representa e ficam de fora.
+func (s *Server) loadFinancingOffers(ctx context.Context) ([]affordableOffer, error) {
+	query := `
+		SELECT banco_financiadora, tipo_financiamento, taxa_juros_mes, taxa_juros_ano, numero_parcelas
+		FROM financiamentos
+		WHERE aprovado = true
+		AND tipo_financiamento = $1
+		AND numero_parcelas > 0
+		AND (taxa_juros_mes IS NOT NULL OR taxa_juros_ano IS NOT NULL)
+	`
+
+	rows, err := s.DB.QueryContext(ctx, query, finance.ModeCDC)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var offers []affordableOffer
+	for rows.Next() {
+		var offer affordableOffer
+		var storedMonthly, storedAnnual sql.NullFloat64
+		if err := rows.Scan(&offer.Bank, &offer.Type, &storedMonthly, &storedAnnual, &offer.Installments); err != nil {
+			return nil, err
+		}
+		// Mesma regra de lookupOffer: a mensal tem precedência e a anual é
+		// convertida por capitalização composta.
+		if storedMonthly.Valid {
+			offer.MonthlyRate = storedMonthly.Float64
+		} else {
+			offer.MonthlyRate = finance.MonthlyFromAnnual(storedAnnual.Float64/100) * 100
+		}
+		offers = append(offers, offer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(offers, func(i, j int) bool { return offers[i].MonthlyRate < offers[j].MonthlyRate })
+	return offers, nil
+}
+
+// maxAffordableVehicles limita quantos veículos disponíveis, dos mais baratos
+// para os mais caros, entram na análise de CheckAffordability.
+const maxAffordableVehicles = 100
+
+// loadVehiclePrices busca os veículos disponíveis com o preço promocional da
+// campanha vigente aplicado e devolve também o total em estoque, para que o
+// corte em maxAffordableVehicles seja informado.
+func (s *Server) loadVehiclePrices(ctx context.Context) ([]affordableVehicle, int, error) {
+	query := `
+		SELECT m.marca, mo.modelo, v.versao, v.preco_venda, COUNT(*) OVER ()
+		FROM veiculos v
+		JOIN modelos mo ON v.id_modelos = mo.id_modelos
+		JOIN marcas m ON mo.id_marcas = m.id_marcas
+		WHERE v.status_veiculo = 'Disponivel'
+		ORDER BY v.preco_venda ASC
+		LIMIT $1
+	`
+
+	rows, err := s.DB.QueryContext(ctx, query, maxAffordableVehicles)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var stock []Vehicle
+	total := 0
+	for rows.Next() {
+		var v Vehicle
+		if err := rows.Scan(&v.Brand, &v.Model, &v.Version, &v.Price, &total); err != nil {
+			return nil, 0, err
+		}
+		stock = append(stock, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if err := s.applyCampaignPrices(ctx, stock); err != nil {
+		return nil, 0, err
+	}
+
+	vehicles := make([]affordableVehicle, 0, len(stock))
+	for _, v := range stock {
+		vehicle := affordableVehicle{Brand: v.Brand, Model: v.Model, Version: v.Version, Price: v.Price}
+		if v.PromotionalPrice > 0 {
+			vehicle.PromotionalPrice = v.PromotionalPrice
+			vehicle.Campaign = v.Campaign
+		}
+		vehicles = append(vehicles, vehicle)
+	}
+	sort.SliceStable(vehicles, func(i, j int) bool { return vehicles[i].price() < vehicles[j].price() })
+	return vehicles, total, nil
+}
@@ -0,0 +1,171 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"mcp-gemini-go/internal/finance"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DiscountedPrice aplica o desconto percentual e depois o desconto em reais.
+func (c Campaign) DiscountedPrice(price float64) float64 {
+	return finance.Round(math.Max(price*(1-c.DiscountPercent/100)-c.DiscountValue, 0))
+}
+
+func (c Campaign) hasDiscount() bool {
+	return c.DiscountPercent > 0 || c.DiscountValue > 0
+}
+
+// loadActiveCampaigns lê as campanhas ativas e dentro da vigência, do modelo
+// informado ou de todos quando model é vazio.
+func (s *Server) loadActiveCampaigns(ctx context.Context, model string) ([]Campaign, error) {
+	query := `
+		SELECT
+			c.nome_campanha,
+			m.marca,
+			mo.modelo,
+			COALESCE(c.descricao, ''),
+			COALESCE(c.desconto_percentual, 0),
+			COALESCE(c.desconto_valor, 0),
+			c.taxa_juros_especial,
+			c.data_inicio,
+			c.data_fim
+		FROM campanhas_promocoes c
+		JOIN modelos mo ON c.id_modelos = mo.id_modelos
+		JOIN marcas m ON mo.id_marcas = m.id_marcas
+		WHERE c.ativa = true
+		AND CURRENT_DATE BETWEEN c.data_inicio AND c.data_fim
+		AND ($1 = '' OR LOWER(mo.modelo) = LOWER($1))
+		ORDER BY c.data_fim ASC
+	`
+
+	rows, err := s.DB.QueryContext(ctx, query, model)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar campanhas: %w", err)
+	}
+	defer rows.Close()
+
+	campaigns := []Campaign{}
+	for rows.Next() {
+		var c Campaign
+		var rate sql.NullFloat64
+		var start, end time.Time
+		if err := rows.Scan(&c.Name, &c.Brand, &c.Model, &c.Description, &c.DiscountPercent, &c.DiscountValue, &rate, &start, &end); err != nil {
+			return nil, fmt.Errorf("erro ao ler campanha: %w", err)
+		}
+		if rate.Valid {
+			c.SpecialRate = &rate.Float64
+		}
+		c.StartDate = start.Format("2006-01-02")
+		c.EndDate = end.Format("2006-01-02")
+		campaigns = append(campaigns, c)
+	}
+	return campaigns, rows.Err()
+}
+
+// bestDiscount devolve a campanha com o menor preço final para price, ou nil
+// se nenhuma dá desconto.
+func bestDiscount(campaigns []Campaign, price float64) *Campaign {
+	var best *Campaign
+	for i := range campaigns {
+		if !campaigns[i].hasDiscount() {
+			continue
+		}
+		if best == nil || campaigns[i].DiscountedPrice(price) < best.DiscountedPrice(price) {
+			best = &campaigns[i]
+		}
+	}
+	return best
+}
+
+// bestRate devolve a campanha com a menor taxa especial, ou nil se nenhuma tem.
+func bestRate(campaigns []Campaign) *Campaign {
+	var best *Campaign
+	for i := range campaigns {
+		if campaigns[i].SpecialRate == nil {
+			continue
+		}
+		if best == nil || *campaigns[i].SpecialRate < *best.SpecialRate {
+			best = &campaigns[i]
+		}
+	}
+	return best
+}
+
+// campaignRate devolve a campanha vigente do modelo cuja taxa especial é menor
+// que rate (fração ao mês), ou nil quando nenhuma melhora a taxa.
+func (s *Server) campaignRate(ctx context.Context, model string, rate float64) (*Campaign, error) {
+	if model == "" {
+		return nil, nil
+	}
+	campaigns, err := s.loadActiveCampaigns(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	campaign := bestRate(campaigns)
+	if campaign == nil || *campaign.SpecialRate/100 >= rate {
+		return nil, nil
+	}
+	return campaign, nil
+}
+
+// financingRate busca a taxa de CDC do banco e a troca pela taxa especial da
+// campanha vigente do modelo quando ela é menor.
+func (s *Server) financingRate(ctx context.Context, bank, model string) (float64, string, *Campaign, *mcp.CallToolResult) {
+	monthlyRate, bank, errResult := s.lookupMonthlyRate(ctx, bank)
+	if errResult != nil {
+		return 0, "", nil, errResult
+	}
+
+	campaign, err := s.campaignRate(ctx, model, monthlyRate)
+	if err != nil {
+		return 0, "", nil, mcp.NewToolResultError(err.Error())
+	}
+	if campaign != nil {
+		monthlyRate = *campaign.SpecialRate / 100
+	}
+	return monthlyRate, bank, campaign, nil
+}
+
+// applyCampaignPrices preenche o preço promocional dos veículos com a campanha
+// de maior desconto vigente para cada modelo.
+func (s *Server) applyCampaignPrices(ctx context.Context, vehicles []Vehicle) error {
+	if len(vehicles) == 0 {
+		return nil
+	}
+
+	campaigns, err := s.loadActiveCampaigns(ctx, "")
+	if err != nil {
+		return err
+	}
+	byModel := map[string][]Campaign{}
+	for _, c := range campaigns {
+		key := strings.ToLower(c.Model)
+		byModel[key] = append(byModel[key], c)
+	}
+
+	for i := range vehicles {
+		if campaign := bestDiscount(byModel[strings.ToLower(vehicles[i].Model)], vehicles[i].Price); campaign != nil {
+			vehicles[i].PromotionalPrice = campaign.DiscountedPrice(vehicles[i].Price)
+			vehicles[i].Campaign = campaign.Name
+		}
+	}
+	return nil
+}
+
+func (s *Server) GetActiveCampaigns(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	campaigns, err := s.loadActiveCampaigns(ctx, request.GetString("model", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	resultJSON, _ := json.Marshal(campaigns)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
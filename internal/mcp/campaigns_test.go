@@ -0,0 +1,92 @@
+package mcp
+
+import "testing"
+
+func TestDiscountedPrice(t *testing.T) {
+	tests := []struct {
+		campaign Campaign
+		want     float64
+	}{
+		{Campaign{DiscountPercent: 10}, 90000},
+		{Campaign{DiscountValue: 5000}, 95000},
+		{Campaign{DiscountPercent: 10, DiscountValue: 5000}, 85000},
+		{Campaign{DiscountValue: 200000}, 0},
+		{Campaign{}, 100000},
+	}
+	for _, tt := range tests {
+		if got := tt.campaign.DiscountedPrice(100000); got != tt.want {
+			t.Errorf("DiscountedPrice(%+v) = %.2f, esperado %.2f", tt.campaign, got, tt.want)
+		}
+	}
+}
+
+func TestBestDiscount(t *testing.T) {
+	// 10% vence os R$ 5.000 acima de R$ 50.000 e perde abaixo.
+	campaigns := []Campaign{
+		{Name: "sem desconto", SpecialRate: floatPtr(0.0099)},
+		{Name: "percentual", DiscountPercent: 10},
+		{Name: "fixo", DiscountValue: 5000},
+		{Name: "fixo repetido", DiscountValue: 5000},
+	}
+	tests := []struct {
+		name  string
+		price float64
+		want  string
+	}{
+		{"carro caro", 80000, "percentual"},
+		{"carro barato", 40000, "fixo"},
+		{"empate fica com a primeira", 50000, "percentual"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best := bestDiscount(campaigns, tt.price)
+			if best == nil || best.Name != tt.want {
+				t.Errorf("bestDiscount = %+v, esperado %s", best, tt.want)
+			}
+		})
+	}
+
+	if best := bestDiscount(campaigns[:1], 80000); best != nil {
+		t.Errorf("campanha só de taxa não dá desconto: %+v", best)
+	}
+	if best := bestDiscount(nil, 80000); best != nil {
+		t.Errorf("sem campanhas: %+v", best)
+	}
+}
+
+func TestBestRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		campaigns []Campaign
+		want      string
+	}{
+		{"menor taxa", []Campaign{
+			{Name: "1,49%", SpecialRate: floatPtr(0.0149)},
+			{Name: "0,99%", SpecialRate: floatPtr(0.0099)},
+			{Name: "desconto", DiscountPercent: 10},
+		}, "0,99%"},
+		{"taxa zero vale", []Campaign{
+			{Name: "0,99%", SpecialRate: floatPtr(0.0099)},
+			{Name: "taxa zero", SpecialRate: floatPtr(0)},
+		}, "taxa zero"},
+		{"empate fica com a primeira", []Campaign{
+			{Name: "primeira", SpecialRate: floatPtr(0.0099)},
+			{Name: "segunda", SpecialRate: floatPtr(0.0099)},
+		}, "primeira"},
+		{"sem taxa especial", []Campaign{{Name: "desconto", DiscountValue: 5000}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best := bestRate(tt.campaigns)
+			if tt.want == "" {
+				if best != nil {
+					t.Errorf("bestRate = %+v, esperado nil", best)
+				}
+				return
+			}
+			if best == nil || best.Name != tt.want {
+				t.Errorf("bestRate = %+v, esperado %s", best, tt.want)
+			}
+		})
+	}
+}
@@ -2,10 +2,20 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
 )
 
+// Client fala o protocolo MCP (initialize, tools/list, tools/call) com o
+// servidor do mesmo processo, de modo que os schemas das ferramentas venham
+// sempre do registro em Server.Initialize.
 type Client struct {
 	Server *Server
+	mcp    *mcpclient.Client
 }
 
 type Tool struct {
@@ -14,97 +24,58 @@ type Tool struct {
 	Parameters  map[string]interface{} `json:"parameters"`
 }
 
-func NewClientWithServer(server *Server) *Client {
+func NewClientWithServer(ctx context.Context, server *Server) (*Client, error) {
+	inProcess, err := mcpclient.NewInProcessClient(server.GetMCPServer())
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar cliente MCP: %w", err)
+	}
+
+	if err := inProcess.Start(ctx); err != nil {
+		return nil, fmt.Errorf("erro ao iniciar cliente MCP: %w", err)
+	}
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "mcp-gemini-go-web",
+		Version: "1.0.0",
+	}
+	if _, err := inProcess.Initialize(ctx, initRequest); err != nil {
+		return nil, fmt.Errorf("erro ao inicializar sessão MCP: %w", err)
+	}
+
 	return &Client{
 		Server: server,
-	}
+		mcp:    inProcess,
+	}, nil
 }
 
 func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
-	return []Tool{
-		{
-			Name:        "get_schema",
-			Description: "Retorna schema do banco com informações dos veículos e financiamentos",
-			Parameters: map[string]interface{}{
-				"type":       "object",
-				"properties": map[string]interface{}{},
-			},
-		},
-		{
-			Name:        "execute_sql",
-			Description: "Executa uma consulta SQL no banco de dados da concessionária",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"query": map[string]interface{}{
-						"type":        "string",
-						"description": "Consulta SQL para executar",
-					},
-				},
-				"required": []string{"query"},
-			},
-		},
-		{
-			Name:        "get_vehicles_available",
-			Description: "Busca veículos disponíveis com filtros opcionais",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"max_price": map[string]interface{}{
-						"type":        "number",
-						"description": "Preço máximo",
-					},
-					"brand": map[string]interface{}{
-						"type":        "string",
-						"description": "Marca do veículo",
-					},
-					"type": map[string]interface{}{
-						"type":        "string",
-						"description": "Tipo do veículo (Novo, Usado, Seminovo)",
-					},
-				},
-			},
-		},
-		{
-			Name:        "get_best_financing",
-			Description: "Busca melhores opções de financiamento",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"vehicle_price": map[string]interface{}{
-						"type":        "number",
-						"description": "Preço do veículo",
-					},
-					"max_installments": map[string]interface{}{
-						"type":        "number",
-						"description": "Número máximo de parcelas",
-					},
-				},
-			},
-		},
-		{
-			Name:        "calculate_financing",
-			Description: "Calcula financiamento com simulação detalhada",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"vehicle_price": map[string]interface{}{
-						"type":        "number",
-						"description": "Preço do veículo",
-					},
-					"down_payment": map[string]interface{}{
-						"type":        "number",
-						"description": "Valor da entrada",
-					},
-					"installments": map[string]interface{}{
-						"type":        "number",
-						"description": "Número de parcelas",
-					},
-				},
-				"required": []string{"vehicle_price", "down_payment", "installments"},
-			},
-		},
-	}, nil
+	result, err := c.mcp.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar ferramentas: %w", err)
+	}
+
+	tools := make([]Tool, 0, len(result.Tools))
+	for _, tool := range result.Tools {
+		parameters := map[string]interface{}{
+			"type":       "object",
+			"properties": tool.InputSchema.Properties,
+		}
+		if tool.InputSchema.Properties == nil {
+			parameters["properties"] = map[string]interface{}{}
+		}
+		if len(tool.InputSchema.Required) > 0 {
+			parameters["required"] = tool.InputSchema.Required
+		}
+
+		tools = append(tools, Tool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  parameters,
+		})
+	}
+	return tools, nil
 }
 
 func (c *Client) FormatToolsForLLM(tools []Tool) []map[string]interface{} {
@@ -121,3 +92,48 @@ func (c *Client) FormatToolsForLLM(tools []Tool) []map[string]interface{} {
 	}
 	return formatted
 }
+
+// CallTool executa uma ferramenta via tools/call e devolve o texto do resultado.
+// Erros reportados pela própria ferramenta também são devolvidos como error.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (string, error) {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+
+	result, err := c.mcp.CallTool(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("erro ao chamar %s: %w", name, err)
+	}
+
+	text := resultText(result)
+	if result.IsError {
+		return "", fmt.Errorf("%s", text)
+	}
+	return text, nil
+}
+
+// CallToolJSON executa a ferramenta e decodifica o resultado JSON em out.
+func (c *Client) CallToolJSON(ctx context.Context, name string, args map[string]interface{}, out interface{}) error {
+	text, err := c.CallTool(ctx, name, args)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(text), out); err != nil {
+		return fmt.Errorf("erro ao decodificar resultado de %s: %w", name, err)
+	}
+	return nil
+}
+
+func (c *Client) Close() error {
+	return c.mcp.Close()
+}
+
+func resultText(result *mcp.CallToolResult) string {
+	var text strings.Builder
+	for _, content := range result.Content {
+		if textContent, ok := mcp.AsTextContent(content); ok {
+			text.WriteString(textContent.Text)
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,257 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"mcp-gemini-go/internal/finance"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	minComparedVehicles = 2
+	maxComparedVehicles = 4
+)
+
+// vehicleFacts são os dados de cada veículo comparado; nil indica que o modelo
+// não tem o dado cadastrado.
+type vehicleFacts struct {
+	vehicle         *vehicleRecord
+	ipva            *float64
+	standardItems   *float64
+	ncap            *float64
+	safetyItems     *float64
+	co2Urban        *float64
+	sustainability  *float64
+	recalls         *float64
+	pendingRecalls  *float64
+	depreciationPct *float64
+}
+
+// comparisonDimension descreve uma linha da comparação.
+type comparisonDimension struct {
+	name         string
+	unit         string
+	higherIsBest bool
+	value        func(f vehicleFacts) *float64
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+func nullFloat(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return floatPtr(value.Float64)
+}
+
+var comparisonDimensions = []comparisonDimension{
+	{"Preço", "R$", false, func(f vehicleFacts) *float64 { return floatPtr(f.vehicle.Price) }},
+	{"Ano modelo", "", true, func(f vehicleFacts) *float64 { return floatPtr(float64(f.vehicle.ModelYear)) }},
+	{"Quilometragem", "km", false, func(f vehicleFacts) *float64 { return floatPtr(f.vehicle.Km) }},
+	{"Potência", "cv", true, func(f vehicleFacts) *float64 { return positive(float64(f.vehicle.Horsepower)) }},
+	{"Consumo urbano", "km/l", true, func(f vehicleFacts) *float64 { return positive(f.vehicle.UrbanConsumption) }},
+	{"Consumo rodoviário", "km/l", true, func(f vehicleFacts) *float64 { return positive(f.vehicle.HighwayConsumption) }},
+	{"IPVA anual", "R$", false, func(f vehicleFacts) *float64 { return f.ipva }},
+	{"Itens de série", "", true, func(f vehicleFacts) *float64 { return f.standardItems }},
+	{"Nota Latin NCAP", "", true, func(f vehicleFacts) *float64 { return f.ncap }},
+	{"Itens de segurança", "", true, func(f vehicleFacts) *float64 { return f.safetyItems }},
+	{"Emissão de CO2 urbana", "g/km", false, func(f vehicleFacts) *float64 { return f.co2Urban }},
+	{"Nota de sustentabilidade", "", true, func(f vehicleFacts) *float64 { return f.sustainability }},
+	{"Recalls e problemas conhecidos", "", false, func(f vehicleFacts) *float64 { return f.recalls }},
+	{"Recalls pendentes", "", false, func(f vehicleFacts) *float64 { return f.pendingRecalls }},
+	{"Depreciação anual", "%", false, func(f vehicleFacts) *float64 { return f.depreciationPct }},
+}
+
+// positive trata zero como dado ausente nas colunas que usam COALESCE(..., 0).
+func positive(value float64) *float64 {
+	if value <= 0 {
+		return nil
+	}
+	return floatPtr(value)
+}
+
+// loadVehicleFacts lê os dados do veículo para a comparação; o IPVA já vem com
+// os incentivos fiscais vigentes na cidade.
+func (s *Server) loadVehicleFacts(ctx context.Context, vehicle *vehicleRecord, city string) (vehicleFacts, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM veiculo_caracteristicas c WHERE c.id_veiculos = $1 AND c.tipo = 'Serie'),
+			sv.nota_latin_ncap,
+			sv.itens,
+			ia.emissao_co2_urbano,
+			ia.nota_sustentabilidade,
+			(SELECT COUNT(*) FROM recalls_problemas r
+				WHERE r.id_modelos = $2
+				AND $3 BETWEEN COALESCE(r.ano_modelo_inicio, $3) AND COALESCE(r.ano_modelo_fim, $3)),
+			(SELECT COUNT(*) FROM recalls_problemas r
+				WHERE r.id_modelos = $2
+				AND $3 BETWEEN COALESCE(r.ano_modelo_inicio, $3) AND COALESCE(r.ano_modelo_fim, $3)
+				AND r.status_solucao <> 'Resolvido')
+		FROM (SELECT 1) base
+		LEFT JOIN LATERAL (
+			SELECT
+				nota_latin_ncap,
+				COALESCE(airbags_frontais, false)::int + COALESCE(airbags_laterais, false)::int +
+				COALESCE(airbags_cortina, false)::int + COALESCE(freios_abs, false)::int +
+				COALESCE(controle_estabilidade, false)::int + COALESCE(controle_tracao, false)::int +
+				COALESCE(assistente_partida_rampa, false)::int + COALESCE(camera_re, false)::int +
+				COALESCE(sensores_estacionamento, false)::int + COALESCE(alerta_ponto_cego, false)::int +
+				COALESCE(frenagem_autonoma_emergencia, false)::int AS itens
+			FROM seguranca_veiculos
+			WHERE id_modelos = $2
+			ORDER BY data_atualizacao DESC
+			LIMIT 1
+		) sv ON true
+		LEFT JOIN LATERAL (
+			SELECT emissao_co2_urbano, nota_sustentabilidade
+			FROM impacto_ambiental
+			WHERE id_modelos = $2
+			ORDER BY data_inclusao DESC
+			LIMIT 1
+		) ia ON true
+	`
+
+	facts := vehicleFacts{vehicle: vehicle}
+	var standardItems, recalls, pendingRecalls float64
+	var ncap, safetyItems, co2, sustainability sql.NullFloat64
+	err := s.DB.QueryRowContext(ctx, query, vehicle.ID, vehicle.ModelID, vehicle.ModelYear).Scan(
+		&standardItems, &ncap, &safetyItems, &co2, &sustainability, &recalls, &pendingRecalls)
+	if err != nil {
+		return facts, fmt.Errorf("erro ao buscar dados de %s %s: %w", vehicle.Brand, vehicle.Model, err)
+	}
+
+	facts.standardItems = positive(standardItems)
+	facts.ncap = nullFloat(ncap)
+	facts.safetyItems = nullFloat(safetyItems)
+	facts.co2Urban = nullFloat(co2)
+	facts.sustainability = nullFloat(sustainability)
+	facts.recalls = floatPtr(recalls)
+	facts.pendingRecalls = floatPtr(pendingRecalls)
+
+	incentives, _, err := s.incentivesFor(ctx, vehicle.ID, city)
+	if err != nil {
+		return facts, err
+	}
+	if vehicle.AnnualIPVA > 0 {
+		ipva, _ := bestIPVA(vehicle.AnnualIPVA, incentives)
+		facts.ipva = floatPtr(ipva)
+	}
+
+	rate, found, err := s.depreciationRate(ctx, vehicle.ModelID)
+	if err != nil {
+		return facts, err
+	}
+	if found {
+		facts.depreciationPct = floatPtr(finance.Round(rate * 100))
+	}
+	return facts, nil
+}
+
+// comparedVehiclesFrom lê vehicle_ids ou a lista vehicles de marca, modelo e
+// versão, na ordem informada.
+func (s *Server) comparedVehiclesFrom(ctx context.Context, request mcp.CallToolRequest) ([]*vehicleRecord, error) {
+	var vehicles []*vehicleRecord
+	for _, id := range request.GetIntSlice("vehicle_ids", nil) {
+		vehicle, err := s.loadVehicle(ctx, id, "", "", "")
+		if err != nil {
+			return nil, fmt.Errorf("veículo %d: %w", id, err)
+		}
+		vehicles = append(vehicles, vehicle)
+	}
+
+	items, _ := request.GetArguments()["vehicles"].([]interface{})
+	for _, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cada item de vehicles deve ter brand, model e version")
+		}
+		brand, _ := fields["brand"].(string)
+		model, _ := fields["model"].(string)
+		version, _ := fields["version"].(string)
+		vehicle, err := s.loadVehicle(ctx, 0, brand, model, version)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s %s: %w", brand, model, version, err)
+		}
+		vehicles = append(vehicles, vehicle)
+	}
+	return vehicles, nil
+}
+
+// CompareVehicles monta a comparação lado a lado, marcando o vencedor de cada
+// dimensão.
+func (s *Server) CompareVehicles(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	vehicles, err := s.comparedVehiclesFrom(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(vehicles) < minComparedVehicles || len(vehicles) > maxComparedVehicles {
+		return mcp.NewToolResultError(fmt.Sprintf("informe de %d a %d veículos em vehicle_ids ou vehicles", minComparedVehicles, maxComparedVehicles)), nil
+	}
+
+	facts := make([]vehicleFacts, len(vehicles))
+	result := VehicleComparison{
+		Vehicles: make([]ComparedVehicle, len(vehicles)),
+		Rows:     make([]ComparisonRow, 0, len(comparisonDimensions)),
+	}
+	city := request.GetString("city", "")
+	for i, vehicle := range vehicles {
+		if facts[i], err = s.loadVehicleFacts(ctx, vehicle, city); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		result.Vehicles[i] = ComparedVehicle{Vehicle: vehicle.Vehicle}
+	}
+
+	for _, dimension := range comparisonDimensions {
+		row := ComparisonRow{
+			Dimension: dimension.name,
+			Unit:      dimension.unit,
+			Better:    "menor",
+			Values:    make([]*float64, len(facts)),
+		}
+		if dimension.higherIsBest {
+			row.Better = "maior"
+		}
+		for i, f := range facts {
+			row.Values[i] = dimension.value(f)
+		}
+
+		if winner := uniqueBest(row.Values, dimension.higherIsBest); winner >= 0 {
+			row.WinnerID = result.Vehicles[winner].ID
+			result.Vehicles[winner].Wins++
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+// uniqueBest devolve o índice do único melhor valor, ou -1 quando há empate ou
+// menos de dois valores para comparar.
+func uniqueBest(values []*float64, higherIsBest bool) int {
+	best, count, tied := -1, 0, false
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		count++
+		switch {
+		case best < 0:
+			best = i
+		case *value == *values[best]:
+			tied = true
+		case (*value > *values[best]) == higherIsBest:
+			best, tied = i, false
+		}
+	}
+	if count < 2 || tied {
+		return -1
+	}
+	return best
+}
@@ -0,0 +1,101 @@
+package mcp
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type clientIdentityKey struct{}
+
+// ClientIdentity identifica o cliente autenticado que chamou uma ferramenta.
+type ClientIdentity struct {
+	Name string
+}
+
+func WithClientIdentity(ctx context.Context, identity ClientIdentity) context.Context {
+	return context.WithValue(ctx, clientIdentityKey{}, identity)
+}
+
+func ClientIdentityFromContext(ctx context.Context) (ClientIdentity, bool) {
+	identity, ok := ctx.Value(clientIdentityKey{}).(ClientIdentity)
+	return identity, ok
+}
+
+// clientName é usado nos logs das ferramentas; chamadas do chat não têm identidade.
+func clientName(ctx context.Context) string {
+	if identity, ok := ClientIdentityFromContext(ctx); ok {
+		return identity.Name
+	}
+	return "chat"
+}
+
+// ParseClientTokens lê tokens no formato "nome:token,nome2:token2" e devolve
+// um mapa de token para nome do cliente.
+func ParseClientTokens(spec string) map[string]string {
+	tokens := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		name, token, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || name == "" || token == "" {
+			continue
+		}
+		tokens[token] = name
+	}
+	return tokens
+}
+
+// HTTPHandler expõe as ferramentas pelos transportes HTTP do MCP sob basePath:
+// streamable HTTP em basePath e SSE em basePath/sse e basePath/message.
+// Toda requisição precisa de um bearer token presente em tokens.
+func (s *Server) HTTPHandler(basePath string, tokens map[string]string) http.Handler {
+	basePath = "/" + strings.Trim(basePath, "/")
+
+	sseServer := server.NewSSEServer(s.mcp, server.WithStaticBasePath(basePath))
+	streamableServer := server.NewStreamableHTTPServer(s.mcp, server.WithEndpointPath(basePath))
+
+	mux := http.NewServeMux()
+	mux.Handle(sseServer.CompleteSsePath(), sseServer)
+	mux.Handle(sseServer.CompleteMessagePath(), sseServer)
+	mux.Handle(basePath, streamableServer)
+
+	return requireBearerToken(tokens, mux)
+}
+
+// requireBearerToken autentica a requisição e coloca a ClientIdentity no
+// contexto, de onde os transportes SSE e streamable HTTP derivam o contexto
+// entregue aos handlers das ferramentas.
+func requireBearerToken(tokens map[string]string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp"`)
+			http.Error(w, "Token de acesso ausente", http.StatusUnauthorized)
+			return
+		}
+
+		name, ok := lookupToken(tokens, strings.TrimSpace(token))
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp", error="invalid_token"`)
+			http.Error(w, "Token de acesso inválido", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := WithClientIdentity(r.Context(), ClientIdentity{Name: name})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func lookupToken(tokens map[string]string, token string) (string, bool) {
+	var name string
+	found := false
+	for candidate, candidateName := range tokens {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			name = candidateName
+			found = true
+		}
+	}
+	return name, found
+}
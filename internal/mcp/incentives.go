@@ -0,0 +1,215 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"mcp-gemini-go/internal/finance"
+
+	"github.com/lib/pq"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Os tipos de incentivo seguem o padrão 'IPVA_Reducao', 'IPVA_Isencao',
+// 'Isencao_Rodizio'.
+func (t TaxIncentive) isIPVA() bool {
+	return strings.HasPrefix(t.Type, "IPVA")
+}
+
+func (t TaxIncentive) isExemption() bool {
+	return strings.Contains(t.Type, "Isencao")
+}
+
+func (t TaxIncentive) isRodizio() bool {
+	return strings.Contains(t.Type, "Rodizio")
+}
+
+// AdjustedIPVA aplica o incentivo ao IPVA anual: isenção zera o imposto; a
+// redução aplica o percentual e depois o valor fixo.
+func (t TaxIncentive) AdjustedIPVA(ipva float64) float64 {
+	if !t.isIPVA() {
+		return ipva
+	}
+	if t.isExemption() {
+		return 0
+	}
+	return finance.Round(math.Max(ipva*(1-t.DiscountPercent/100)-t.DiscountValue, 0))
+}
+
+// bestIPVA devolve o menor IPVA entre os incentivos, que não se acumulam, e o
+// incentivo aplicado (nil quando nenhum reduz o imposto).
+func bestIPVA(ipva float64, incentives []TaxIncentive) (float64, *TaxIncentive) {
+	best, applied := ipva, (*TaxIncentive)(nil)
+	for i := range incentives {
+		if adjusted := incentives[i].AdjustedIPVA(ipva); adjusted < best {
+			best, applied = adjusted, &incentives[i]
+		}
+	}
+	return best, applied
+}
+
+// loadIncentives lê, numa só consulta, os incentivos vigentes para o modelo e
+// o combustível de cada veículo, agrupados pelo id. Com cityID zero só entram
+// os incentivos sem restrição de cidade.
+func (s *Server) loadIncentives(ctx context.Context, vehicleIDs []int, cityID int) (map[int][]TaxIncentive, error) {
+	incentives := map[int][]TaxIncentive{}
+	if len(vehicleIDs) == 0 {
+		return incentives, nil
+	}
+
+	query := `
+		SELECT
+			v.id_veiculos,
+			i.tipo_incentivo,
+			COALESCE(i.descricao, ''),
+			COALESCE(i.percentual_desconto, 0),
+			COALESCE(i.valor_desconto_fixo, 0),
+			COALESCE(ci.cidade, ''),
+			i.vigencia_inicio,
+			i.vigencia_fim,
+			COALESCE(i.condicoes, ''),
+			COALESCE(i.orgao_responsavel, ''),
+			COALESCE(i.lei_decreto, '')
+		FROM incentivos_fiscais i
+		JOIN veiculos v ON v.id_veiculos = ANY($1)
+		LEFT JOIN cidades ci ON i.id_cidades = ci.id_cidades
+		WHERE COALESCE(i.ativo, true)
+		AND CURRENT_DATE BETWEEN COALESCE(i.vigencia_inicio, CURRENT_DATE) AND COALESCE(i.vigencia_fim, CURRENT_DATE)
+		AND (i.id_modelos IS NULL OR i.id_modelos = v.id_modelos)
+		AND (i.tipo_combustivel IS NULL OR LOWER(i.tipo_combustivel) = LOWER(v.tipo_combustivel))
+		AND (i.id_cidades IS NULL OR i.id_cidades = $2)
+		ORDER BY v.id_veiculos, i.tipo_incentivo, i.vigencia_fim ASC NULLS LAST
+	`
+
+	ids := make([]int64, len(vehicleIDs))
+	for i, id := range vehicleIDs {
+		ids[i] = int64(id)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, query, pq.Array(ids), cityID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar incentivos fiscais: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var t TaxIncentive
+		var start, end sql.NullTime
+		if err := rows.Scan(&id, &t.Type, &t.Description, &t.DiscountPercent, &t.DiscountValue, &t.City,
+			&start, &end, &t.Conditions, &t.Agency, &t.Law); err != nil {
+			return nil, fmt.Errorf("erro ao ler incentivo fiscal: %w", err)
+		}
+		if start.Valid {
+			t.StartDate = start.Time.Format("2006-01-02")
+		}
+		if end.Valid {
+			t.EndDate = end.Time.Format("2006-01-02")
+		}
+		incentives[id] = append(incentives[id], t)
+	}
+	return incentives, rows.Err()
+}
+
+// incentivesFor resolve a cidade do cliente e lê os incentivos do veículo. A
+// observação devolvida avisa quando a cidade não está cadastrada.
+func (s *Server) incentivesFor(ctx context.Context, vehicleID int, cityName string) ([]TaxIncentive, string, error) {
+	var cityID int
+	var note string
+	if cityName != "" {
+		city, err := s.findCity(ctx, cityName)
+		if err != nil {
+			return nil, "", err
+		}
+		if city != nil {
+			cityID = city.ID
+		} else {
+			note = fmt.Sprintf("cidade %s não cadastrada; só os incentivos sem restrição de cidade foram considerados", cityName)
+		}
+	}
+
+	incentives, err := s.loadIncentives(ctx, []int{vehicleID}, cityID)
+	if err != nil {
+		return nil, "", err
+	}
+	if incentives[vehicleID] == nil {
+		return []TaxIncentive{}, note, nil
+	}
+	return incentives[vehicleID], note, nil
+}
+
+// applyIncentives preenche o IPVA com incentivo dos veículos listados, usando
+// só os incentivos sem restrição de cidade.
+func (s *Server) applyIncentives(ctx context.Context, vehicles []Vehicle) error {
+	ids := make([]int, len(vehicles))
+	for i, v := range vehicles {
+		ids[i] = v.ID
+	}
+
+	incentives, err := s.loadIncentives(ctx, ids, 0)
+	if err != nil {
+		return err
+	}
+	for i := range vehicles {
+		if ipva, applied := bestIPVA(vehicles[i].AnnualIPVA, incentives[vehicles[i].ID]); applied != nil {
+			vehicles[i].AdjustedIPVA = floatPtr(ipva)
+		}
+	}
+	return nil
+}
+
+type taxIncentiveResult struct {
+	Vehicle       Vehicle        `json:"veiculo"`
+	City          string         `json:"cidade,omitempty"`
+	Incentives    []TaxIncentive `json:"incentivos"`
+	AnnualIPVA    float64        `json:"ipva_anual"`
+	AdjustedIPVA  float64        `json:"ipva_ajustado"`
+	IPVASavings   float64        `json:"economia_ipva_anual"`
+	IPVAIncentive string         `json:"incentivo_ipva_aplicado,omitempty"`
+	RodizioExempt bool           `json:"isencao_rodizio"`
+	Notes         []string       `json:"observacoes,omitempty"`
+}
+
+// GetTaxIncentives lista os incentivos fiscais vigentes do veículo na cidade
+// do cliente e calcula o IPVA ajustado.
+func (s *Server) GetTaxIncentives(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	vehicle, err := s.loadVehicle(ctx, request.GetInt("vehicle_id", 0), request.GetString("brand", ""), request.GetString("model", ""), request.GetString("version", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	city := request.GetString("city", "")
+	incentives, note, err := s.incentivesFor(ctx, vehicle.ID, city)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	result := taxIncentiveResult{
+		Vehicle:    vehicle.Vehicle,
+		City:       city,
+		Incentives: incentives,
+		AnnualIPVA: vehicle.AnnualIPVA,
+	}
+	if note != "" {
+		result.Notes = append(result.Notes, note)
+	}
+
+	var applied *TaxIncentive
+	result.AdjustedIPVA, applied = bestIPVA(vehicle.AnnualIPVA, incentives)
+	result.IPVASavings = finance.Round(vehicle.AnnualIPVA - result.AdjustedIPVA)
+	if applied != nil {
+		result.IPVAIncentive = applied.Description
+	}
+	for _, t := range incentives {
+		if t.isRodizio() && t.isExemption() {
+			result.RodizioExempt = true
+		}
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
@@ -0,0 +1,59 @@
+package mcp
+
+import "testing"
+
+func TestAdjustedIPVA(t *testing.T) {
+	tests := []struct {
+		name      string
+		incentive TaxIncentive
+		want      float64
+	}{
+		{"isenção", TaxIncentive{Type: "IPVA_Isencao"}, 0},
+		{"redução percentual", TaxIncentive{Type: "IPVA_Reducao", DiscountPercent: 50}, 2000},
+		{"percentual e depois valor", TaxIncentive{Type: "IPVA_Reducao", DiscountPercent: 50, DiscountValue: 500}, 1500},
+		{"valor acima do imposto", TaxIncentive{Type: "IPVA_Reducao", DiscountValue: 10000}, 0},
+		{"rodízio não mexe no IPVA", TaxIncentive{Type: "Isencao_Rodizio"}, 4000},
+	}
+	for _, tt := range tests {
+		if got := tt.incentive.AdjustedIPVA(4000); got != tt.want {
+			t.Errorf("%s: AdjustedIPVA = %.2f, esperado %.2f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBestIPVA(t *testing.T) {
+	tests := []struct {
+		name       string
+		incentives []TaxIncentive
+		ipva       float64
+		applied    string
+	}{
+		{"sem incentivos", nil, 4000, ""},
+		{"só rodízio", []TaxIncentive{{Type: "Isencao_Rodizio", Description: "rodízio"}}, 4000, ""},
+		{"não se acumulam", []TaxIncentive{
+			{Type: "IPVA_Reducao", Description: "30%", DiscountPercent: 30},
+			{Type: "IPVA_Reducao", Description: "50%", DiscountPercent: 50},
+			{Type: "IPVA_Reducao", Description: "R$ 1.000", DiscountValue: 1000},
+		}, 2000, "50%"},
+		{"isenção vence a redução", []TaxIncentive{
+			{Type: "IPVA_Reducao", Description: "50%", DiscountPercent: 50},
+			{Type: "IPVA_Isencao", Description: "isenção"},
+		}, 0, "isenção"},
+		{"empate fica com o primeiro", []TaxIncentive{
+			{Type: "IPVA_Reducao", Description: "primeiro", DiscountValue: 2000},
+			{Type: "IPVA_Reducao", Description: "segundo", DiscountPercent: 50},
+		}, 2000, "primeiro"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ipva, applied := bestIPVA(4000, tt.incentives)
+			description := ""
+			if applied != nil {
+				description = applied.Description
+			}
+			if ipva != tt.ipva || description != tt.applied {
+				t.Errorf("bestIPVA = %.2f, %q; esperado %.2f, %q", ipva, description, tt.ipva, tt.applied)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"mcp-gemini-go/internal/finance"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Como a cotação encontrada corresponde ao perfil do cliente.
+const (
+	matchExact    = "exata"
+	matchNeighbor = "segmento_vizinho"
+	matchNational = "media_nacional"
+)
+
+// Limites do ajuste pelo índice de roubo e furto quando a cotação vem de outra
+// cidade ou da média nacional.
+const (
+	minTheftFactor = 0.5
+	maxTheftFactor = 2.0
+)
+
+// driverProfile é o perfil usado na cotação de seguro. Campos zerados não
+// restringem a busca.
+type driverProfile struct {
+	Age          int    `json:"idade,omitempty"`
+	Gender       string `json:"genero,omitempty"`
+	City         string `json:"cidade,omitempty"`
+	LicenseYears *int   `json:"tempo_cnh_anos,omitempty"`
+}
+
+func driverProfileFrom(request mcp.CallToolRequest) driverProfile {
+	profile := driverProfile{
+		Age:    request.GetInt("driver_age", 0),
+		Gender: request.GetString("driver_gender", ""),
+		City:   request.GetString("city", ""),
+	}
+	if _, ok := request.GetArguments()["license_years"]; ok {
+		years := request.GetInt("license_years", 0)
+		profile.LicenseYears = &years
+	}
+	return profile
+}
+
+// insuranceSegment é uma linha de custos_seguro_detalhados.
+type insuranceSegment struct {
+	CityID       int
+	City         string
+	State        string
+	AgeMin       int
+	AgeMax       int
+	LicenseYears int
+	Gender       string
+	Coverage     string
+	Average      float64
+	Minimum      float64
+	Maximum      float64
+	Deductible   float64
+	Insurer      string
+	QuoteDate    sql.NullTime
+}
+
+// customerCity é a cidade do cliente encontrada no cadastro.
+type customerCity struct {
+	ID    int
+	Name  string
+	State string
+}
+
+// theftIndex é o índice de roubo e furto do modelo em uma cidade.
+type theftIndex struct {
+	City        string  `json:"cidade"`
+	Year        int     `json:"ano_referencia"`
+	PerThousand float64 `json:"indice_roubo_por_mil"`
+	Ranking     int     `json:"ranking_nacional,omitempty"`
+	Average     float64 `json:"media_modelo,omitempty"`
+}
+
+type insuranceEstimate struct {
+	Vehicle         Vehicle       `json:"veiculo"`
+	Profile         driverProfile `json:"perfil_condutor"`
+	Coverage        string        `json:"cobertura,omitempty"`
+	Match           string        `json:"correspondencia"`
+	Adjusted        []string      `json:"criterios_ajustados,omitempty"`
+	ReferenceCity   string        `json:"cidade_referencia,omitempty"`
+	Minimum         float64       `json:"premio_anual_minimo"`
+	Average         float64       `json:"premio_anual_medio"`
+	Maximum         float64       `json:"premio_anual_maximo"`
+	Monthly         float64       `json:"premio_mensal_medio"`
+	Deductible      float64       `json:"franquia"`
+	Insurer         string        `json:"seguradora_exemplo,omitempty"`
+	QuoteDate       string        `json:"data_cotacao,omitempty"`
+	Theft           *theftIndex   `json:"roubo_furto,omitempty"`
+	TheftAdjustment float64       `json:"fator_roubo_furto,omitempty"`
+	Notes           []string      `json:"observacoes,omitempty"`
+}
+
+// mismatch pontua a distância do segmento ao perfil; zero é correspondência
+// exata. Os critérios que não batem são devolvidos para informar o cliente.
+func (seg insuranceSegment) mismatch(profile driverProfile, city *customerCity, coverage string) (float64, []string) {
+	var score float64
+	var adjusted []string
+	if city != nil && seg.CityID != city.ID {
+		if seg.State == city.State {
+			score++
+		} else {
+			score += 2
+		}
+		adjusted = append(adjusted, "cidade")
+	}
+	if profile.Age > 0 && (profile.Age < seg.AgeMin || profile.Age > seg.AgeMax) {
+		distance := math.Min(math.Abs(float64(profile.Age-seg.AgeMin)), math.Abs(float64(profile.Age-seg.AgeMax)))
+		score += 1 + distance/10
+		adjusted = append(adjusted, "idade")
+	}
+	if profile.Gender != "" && seg.Gender != "Unissex" && seg.Gender != profile.Gender {
+		score++
+		adjusted = append(adjusted, "genero")
+	}
+	if profile.LicenseYears != nil && seg.LicenseYears > *profile.LicenseYears {
+		score++
+		adjusted = append(adjusted, "tempo_cnh")
+	}
+	if coverage != "" && !strings.EqualFold(seg.Coverage, coverage) {
+		score += 1.5
+		adjusted = append(adjusted, "cobertura")
+	}
+	return score, adjusted
+}
+
+func (s *Server) insuranceSegments(ctx context.Context, modelID int) ([]insuranceSegment, error) {
+	query := `
+		SELECT
+			s.id_cidades,
+			ci.cidade,
+			e.sigla,
+			COALESCE(s.idade_condutor_min, 18),
+			COALESCE(s.idade_condutor_max, 100),
+			COALESCE(s.tempo_cnh_anos, 0),
+			COALESCE(s.genero, 'Unissex'),
+			COALESCE(s.cobertura_tipo, ''),
+			s.valor_anual_medio,
+			COALESCE(s.valor_anual_minimo, s.valor_anual_medio),
+			COALESCE(s.valor_anual_maximo, s.valor_anual_medio),
+			COALESCE(s.franquia_media, 0),
+			COALESCE(s.seguradora_exemplo, ''),
+			s.data_cotacao
+		FROM custos_seguro_detalhados s
+		JOIN cidades ci ON s.id_cidades = ci.id_cidades
+		JOIN estados e ON ci.id_estados = e.id_estados
+		WHERE s.id_modelos = $1
+		ORDER BY s.data_cotacao DESC NULLS LAST
+	`
+
+	rows, err := s.DB.QueryContext(ctx, query, modelID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar cotações de seguro: %w", err)
+	}
+	defer rows.Close()
+
+	var segments []insuranceSegment
+	for rows.Next() {
+		var seg insuranceSegment
+		if err := rows.Scan(&seg.CityID, &seg.City, &seg.State, &seg.AgeMin, &seg.AgeMax, &seg.LicenseYears, &seg.Gender,
+			&seg.Coverage, &seg.Average, &seg.Minimum, &seg.Maximum, &seg.Deductible, &seg.Insurer, &seg.QuoteDate); err != nil {
+			return nil, fmt.Errorf("erro ao ler cotação de seguro: %w", err)
+		}
+		segments = append(segments, seg)
+	}
+	return segments, rows.Err()
+}
+
+func (s *Server) findCity(ctx context.Context, name string) (*customerCity, error) {
+	query := `
+		SELECT ci.id_cidades, ci.cidade, e.sigla
+		FROM cidades ci
+		JOIN estados e ON ci.id_estados = e.id_estados
+		WHERE LOWER(ci.cidade) = LOWER($1)
+		LIMIT 1
+	`
+
+	var city customerCity
+	err := s.DB.QueryRowContext(ctx, query, name).Scan(&city.ID, &city.Name, &city.State)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar cidade: %w", err)
+	}
+	return &city, nil
+}
+
+// theftIndexFor busca o índice mais recente do modelo na cidade, com a média
+// do modelo entre as cidades cadastradas. Sem dados devolve nil.
+func (s *Server) theftIndexFor(ctx context.Context, modelID, cityID int) (*theftIndex, error) {
+	query := `
+		SELECT
+			ci.cidade,
+			i.ano_referencia,
+			i.indice_roubo_por_mil,
+			COALESCE(i.ranking_nacional, 0),
+			(SELECT AVG(a.indice_roubo_por_mil) FROM indices_roubo_furto a WHERE a.id_modelos = i.id_modelos)
+		FROM indices_roubo_furto i
+		JOIN cidades ci ON i.id_cidades = ci.id_cidades
+		WHERE i.id_modelos = $1 AND i.id_cidades = $2 AND i.indice_roubo_por_mil IS NOT NULL
+		ORDER BY i.ano_referencia DESC
+		LIMIT 1
+	`
+
+	var index theftIndex
+	err := s.DB.QueryRowContext(ctx, query, modelID, cityID).Scan(&index.City, &index.Year, &index.PerThousand, &index.Ranking, &index.Average)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar índice de roubo e furto: %w", err)
+	}
+	index.Average = finance.Round(index.Average)
+	return &index, nil
+}
+
+// nationalInsurance estima o prêmio pela média nacional da relação entre prêmio
+// e preço dos modelos cadastrados. Sem cotações devolve nil.
+func (s *Server) nationalInsurance(ctx context.Context, price float64, coverage string) (*insuranceEstimate, error) {
+	query := `
+		SELECT
+			AVG(s.valor_anual_medio / p.preco),
+			AVG(COALESCE(s.valor_anual_minimo, s.valor_anual_medio) / p.preco),
+			AVG(COALESCE(s.valor_anual_maximo, s.valor_anual_medio) / p.preco),
+			AVG(COALESCE(s.franquia_media, 0) / p.preco)
+		FROM custos_seguro_detalhados s
+		JOIN (
+			SELECT id_modelos, AVG(preco_venda) AS preco
+			FROM veiculos
+			WHERE preco_venda > 0
+			GROUP BY id_modelos
+		) p ON s.id_modelos = p.id_modelos
+		WHERE ($1 = '' OR LOWER(s.cobertura_tipo) = LOWER($1))
+	`
+
+	var average, minimum, maximum, deductible sql.NullFloat64
+	if err := s.DB.QueryRowContext(ctx, query, coverage).Scan(&average, &minimum, &maximum, &deductible); err != nil {
+		return nil, fmt.Errorf("erro ao calcular a média nacional de seguro: %w", err)
+	}
+	if !average.Valid {
+		return nil, nil
+	}
+	return &insuranceEstimate{
+		Match:      matchNational,
+		Minimum:    finance.Round(price * minimum.Float64),
+		Average:    finance.Round(price * average.Float64),
+		Maximum:    finance.Round(price * maximum.Float64),
+		Deductible: finance.Round(price * deductible.Float64),
+	}, nil
+}
+
+// estimateInsurance procura o segmento de seguro do modelo mais próximo do
+// perfil e, sem cotações do modelo, usa a média nacional. Quando a cotação não
+// é da cidade do cliente, ajusta os prêmios pelo índice de roubo e furto.
+// Devolve nil se não há nenhuma cotação cadastrada.
+func (s *Server) estimateInsurance(ctx context.Context, vehicle *vehicleRecord, profile driverProfile, coverage string) (*insuranceEstimate, error) {
+	estimate := &insuranceEstimate{Vehicle: vehicle.Vehicle, Profile: profile, Coverage: coverage}
+
+	var city *customerCity
+	if profile.City != "" {
+		var err error
+		if city, err = s.findCity(ctx, profile.City); err != nil {
+			return nil, err
+		}
+		if city == nil {
+			estimate.Notes = append(estimate.Notes, fmt.Sprintf("cidade %s não cadastrada; a cidade não foi considerada", profile.City))
+		}
+	}
+
+	segments, err := s.insuranceSegments(ctx, vehicle.ModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	var reference *insuranceSegment
+	if len(segments) > 0 {
+		type candidate struct {
+			segment  *insuranceSegment
+			score    float64
+			adjusted []string
+		}
+		candidates := make([]candidate, len(segments))
+		for i := range segments {
+			score, adjusted := segments[i].mismatch(profile, city, coverage)
+			candidates[i] = candidate{&segments[i], score, adjusted}
+		}
+		// Os segmentos vêm da cotação mais recente para a mais antiga.
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score < candidates[j].score })
+
+		best := candidates[0]
+		reference = best.segment
+		estimate.Match = matchExact
+		if best.score > 0 {
+			estimate.Match = matchNeighbor
+			estimate.Adjusted = best.adjusted
+		}
+		estimate.ReferenceCity = reference.City
+		estimate.Minimum = reference.Minimum
+		estimate.Average = reference.Average
+		estimate.Maximum = reference.Maximum
+		estimate.Deductible = reference.Deductible
+		estimate.Insurer = reference.Insurer
+		if reference.QuoteDate.Valid {
+			estimate.QuoteDate = reference.QuoteDate.Time.Format("2006-01-02")
+		}
+		if coverage == "" {
+			estimate.Coverage = reference.Coverage
+		}
+	} else {
+		national, err := s.nationalInsurance(ctx, vehicle.Price, coverage)
+		if err != nil || national == nil {
+			return nil, err
+		}
+		estimate.Match = national.Match
+		estimate.Minimum = national.Minimum
+		estimate.Average = national.Average
+		estimate.Maximum = national.Maximum
+		estimate.Deductible = national.Deductible
+		estimate.Notes = append(estimate.Notes, fmt.Sprintf("sem cotações para %s %s; prêmio estimado pela média nacional em relação ao preço", vehicle.Brand, vehicle.Model))
+	}
+
+	if city == nil {
+		return estimate, nil
+	}
+	if estimate.Theft, err = s.theftIndexFor(ctx, vehicle.ModelID, city.ID); err != nil {
+		return nil, err
+	}
+	if estimate.Theft == nil || (reference != nil && reference.CityID == city.ID) {
+		return estimate, nil
+	}
+
+	// A cotação de outra cidade (ou a média nacional) é corrigida pelo risco
+	// relativo de roubo e furto da cidade do cliente.
+	baseline := estimate.Theft.Average
+	if reference != nil {
+		referenceIndex, err := s.theftIndexFor(ctx, vehicle.ModelID, reference.CityID)
+		if err != nil {
+			return nil, err
+		}
+		baseline = 0
+		if referenceIndex != nil {
+			baseline = referenceIndex.PerThousand
+		}
+	}
+	if baseline > 0 && estimate.Theft.PerThousand > 0 {
+		factor := math.Min(math.Max(estimate.Theft.PerThousand/baseline, minTheftFactor), maxTheftFactor)
+		estimate.TheftAdjustment = finance.Round(factor)
+		estimate.Minimum = finance.Round(estimate.Minimum * factor)
+		estimate.Average = finance.Round(estimate.Average * factor)
+		estimate.Maximum = finance.Round(estimate.Maximum * factor)
+	}
+	return estimate, nil
+}
+
+// EstimateInsurance estima o prêmio anual do seguro para o perfil do condutor.
+func (s *Server) EstimateInsurance(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	vehicle, err := s.loadVehicle(ctx, request.GetInt("vehicle_id", 0), request.GetString("brand", ""), request.GetString("model", ""), request.GetString("version", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	estimate, err := s.estimateInsurance(ctx, vehicle, driverProfileFrom(request), request.GetString("coverage", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if estimate == nil {
+		return mcp.NewToolResultError("não há cotações de seguro cadastradas para estimar o prêmio"), nil
+	}
+	estimate.Monthly = finance.Round(estimate.Average / 12)
+
+	resultJSON, _ := json.Marshal(estimate)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
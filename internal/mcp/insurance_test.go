@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestInsuranceSegmentMismatch(t *testing.T) {
+	segment := insuranceSegment{
+		CityID: 1, State: "SP", AgeMin: 26, AgeMax: 35, LicenseYears: 2,
+		Gender: "Masculino", Coverage: "Completa",
+	}
+	city := &customerCity{ID: 1, State: "SP"}
+	years := func(n int) *int { return &n }
+
+	tests := []struct {
+		name     string
+		profile  driverProfile
+		city     *customerCity
+		coverage string
+		score    float64
+		adjusted []string
+	}{
+		{"exata", driverProfile{Age: 30, Gender: "Masculino", LicenseYears: years(5)}, city, "completa", 0, nil},
+		{"perfil vazio não restringe", driverProfile{}, nil, "", 0, nil},
+		{"outra cidade do estado", driverProfile{}, &customerCity{ID: 2, State: "SP"}, "", 1, []string{"cidade"}},
+		{"outro estado", driverProfile{}, &customerCity{ID: 3, State: "RJ"}, "", 2, []string{"cidade"}},
+		{"idade abaixo da faixa", driverProfile{Age: 21}, city, "", 1.5, []string{"idade"}},
+		{"idade acima da faixa", driverProfile{Age: 55}, city, "", 3, []string{"idade"}},
+		{"gênero", driverProfile{Gender: "Feminino"}, city, "", 1, []string{"genero"}},
+		{"habilitação recente", driverProfile{LicenseYears: years(1)}, city, "", 1, []string{"tempo_cnh"}},
+		{"cobertura", driverProfile{}, city, "Terceiros", 1.5, []string{"cobertura"}},
+		{"vários critérios", driverProfile{Age: 30, Gender: "Feminino"}, &customerCity{ID: 3, State: "RJ"}, "Terceiros",
+			4.5, []string{"cidade", "genero", "cobertura"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, adjusted := segment.mismatch(tt.profile, tt.city, tt.coverage)
+			if math.Abs(score-tt.score) > 1e-9 || !reflect.DeepEqual(adjusted, tt.adjusted) {
+				t.Errorf("mismatch = %v, %v; esperado %v, %v", score, adjusted, tt.score, tt.adjusted)
+			}
+		})
+	}
+
+	unisex := segment
+	unisex.Gender = "Unissex"
+	if score, _ := unisex.mismatch(driverProfile{Gender: "Feminino"}, nil, ""); score != 0 {
+		t.Errorf("segmento unissex atende qualquer gênero, pontuação %v", score)
+	}
+}
@@ -0,0 +1,276 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"mcp-gemini-go/internal/finance"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// scarceParts são as disponibilidades de peças que geram alerta no plano.
+var scarceParts = map[string]bool{"Baixa": true, "Rara": true}
+
+// maintenanceService é um serviço de custos_manutencao_detalhados com a
+// divisão entre peças e mão de obra.
+type maintenanceService struct {
+	finance.MaintenanceItem
+	Parts             float64 `json:"valor_pecas"`
+	Labour            float64 `json:"valor_mao_obra"`
+	PartsAvailability string  `json:"disponibilidade_pecas,omitempty"`
+	LabourEase        string  `json:"facilidade_mao_obra,omitempty"`
+}
+
+// maintenanceServices lê os serviços recorrentes cadastrados para o modelo.
+func (s *Server) maintenanceServices(ctx context.Context, modelID int) ([]maintenanceService, error) {
+	query := `
+		SELECT
+			tipo_custo,
+			COALESCE(quilometragem_referencia, 0),
+			COALESCE(periodicidade_meses, 0),
+			valor_total_medio,
+			COALESCE(valor_medio_peca, 0),
+			COALESCE(valor_medio_mao_obra, 0),
+			COALESCE(disponibilidade_pecas, ''),
+			COALESCE(facilidade_mao_obra, '')
+		FROM custos_manutencao_detalhados
+		WHERE id_modelos = $1
+		AND (quilometragem_referencia > 0 OR periodicidade_meses > 0)
+		ORDER BY quilometragem_referencia ASC NULLS LAST
+	`
+
+	rows, err := s.DB.QueryContext(ctx, query, modelID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar custos de manutenção: %w", err)
+	}
+	defer rows.Close()
+
+	var services []maintenanceService
+	for rows.Next() {
+		var m maintenanceService
+		if err := rows.Scan(&m.Name, &m.IntervalKm, &m.IntervalMonths, &m.Cost,
+			&m.Parts, &m.Labour, &m.PartsAvailability, &m.LabourEase); err != nil {
+			return nil, fmt.Errorf("erro ao ler custo de manutenção: %w", err)
+		}
+		services = append(services, m)
+	}
+	return services, rows.Err()
+}
+
+// maintenanceItems lê os serviços recorrentes cadastrados para o modelo, só com
+// intervalos e custo total.
+func (s *Server) maintenanceItems(ctx context.Context, modelID int) ([]finance.MaintenanceItem, error) {
+	services, err := s.maintenanceServices(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]finance.MaintenanceItem, len(services))
+	for i, m := range services {
+		items[i] = m.MaintenanceItem
+	}
+	return items, nil
+}
+
+// maintenanceRecord é um serviço já feito no veículo, de historico_manutencao.
+type maintenanceRecord struct {
+	Type        string  `json:"tipo_servico"`
+	Description string  `json:"descricao,omitempty"`
+	Km          int     `json:"quilometragem"`
+	Value       float64 `json:"valor_servico"`
+	Date        string  `json:"data_servico"`
+	date        time.Time
+}
+
+func (s *Server) maintenanceHistory(ctx context.Context, vehicleID int) ([]maintenanceRecord, error) {
+	query := `
+		SELECT tipo_servico, COALESCE(descricao, ''), COALESCE(quilometragem, 0), COALESCE(valor_servico, 0), data_servico
+		FROM historico_manutencao
+		WHERE id_veiculos = $1
+		ORDER BY data_servico DESC
+	`
+
+	rows, err := s.DB.QueryContext(ctx, query, vehicleID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar histórico de manutenção: %w", err)
+	}
+	defer rows.Close()
+
+	var records []maintenanceRecord
+	for rows.Next() {
+		var r maintenanceRecord
+		if err := rows.Scan(&r.Type, &r.Description, &r.Km, &r.Value, &r.date); err != nil {
+			return nil, fmt.Errorf("erro ao ler histórico de manutenção: %w", err)
+		}
+		r.Date = r.date.Format("2006-01-02")
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+var accentReplacer = strings.NewReplacer("á", "a", "à", "a", "â", "a", "ã", "a", "é", "e", "ê", "e", "í", "i", "ó", "o", "ô", "o", "õ", "o", "ú", "u", "ç", "c")
+
+// serviceKey é a primeira palavra do serviço sem acentos ('Revisao_10k' e
+// 'Revisão' dão 'revisao'), usada para casar o catálogo com o histórico.
+func serviceKey(name string) string {
+	fields := strings.FieldsFunc(accentReplacer.Replace(strings.ToLower(name)), func(r rune) bool {
+		return r == '_' || r == ' ' || r == '-'
+	})
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// lastServices devolve, para cada serviço do catálogo, o último registro do
+// histórico que casa com ele. O histórico vem do mais recente para o mais antigo.
+func lastServices(services []maintenanceService, history []maintenanceRecord, now time.Time) []*finance.MaintenanceState {
+	last := make([]*finance.MaintenanceState, len(services))
+	for i, m := range services {
+		key := serviceKey(m.Name)
+		for _, r := range history {
+			text := accentReplacer.Replace(strings.ToLower(r.Type + " " + r.Description))
+			if key == "" || !strings.Contains(text, key) {
+				continue
+			}
+			monthsAgo := (now.Year()-r.date.Year())*12 + int(now.Month()) - int(r.date.Month())
+			last[i] = &finance.MaintenanceState{Km: float64(r.Km), Month: -monthsAgo}
+			break
+		}
+	}
+	return last
+}
+
+type plannedService struct {
+	Date    string  `json:"data"`
+	Km      float64 `json:"quilometragem"`
+	Service string  `json:"servico"`
+	Parts   float64 `json:"valor_pecas"`
+	Labour  float64 `json:"valor_mao_obra"`
+	Total   float64 `json:"valor_total"`
+	Overdue bool    `json:"vencido,omitempty"`
+}
+
+type maintenanceYear struct {
+	Year  int     `json:"ano"`
+	Total float64 `json:"total"`
+}
+
+type maintenancePlan struct {
+	Vehicle        Vehicle              `json:"veiculo"`
+	StartKm        float64              `json:"km_inicial"`
+	AnnualKm       float64              `json:"km_anual"`
+	Months         int                  `json:"meses"`
+	Services       []maintenanceService `json:"servicos"`
+	Schedule       []plannedService     `json:"plano"`
+	YearTotals     []maintenanceYear    `json:"totais_por_ano"`
+	Total          float64              `json:"custo_total"`
+	MonthlyAverage float64              `json:"custo_mensal_medio"`
+	Alerts         []string             `json:"alertas,omitempty"`
+	History        []maintenanceRecord  `json:"servicos_realizados,omitempty"`
+}
+
+// maxPlanMonths é o horizonte máximo do plano de manutenção.
+const maxPlanMonths = 120
+
+// GetMaintenancePlan monta o plano de manutenção datado para os primeiros anos
+// ou quilômetros de uso, descontando os serviços já feitos em veículos usados.
+func (s *Server) GetMaintenancePlan(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	annualKm := request.GetFloat("annual_km", 15000)
+	if annualKm <= 0 {
+		return mcp.NewToolResultError("a quilometragem anual deve ser maior que zero"), nil
+	}
+
+	// O plano termina no que vier primeiro entre os anos e os quilômetros, e
+	// nunca passa de maxPlanMonths.
+	years := request.GetInt("years", 0)
+	km := request.GetFloat("km", 0)
+	if years < 0 || years > maxPlanMonths/12 || km < 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("o horizonte deve ficar entre 1 e %d anos e a quilometragem não pode ser negativa", maxPlanMonths/12)), nil
+	}
+	months := years * 12
+	if km > 0 {
+		// A conta em float evita estourar int com quilometragens absurdas.
+		byKm := math.Ceil(km / annualKm * 12)
+		if byKm > maxPlanMonths {
+			return mcp.NewToolResultError(fmt.Sprintf("%.0f km a %.0f km por ano passam do horizonte máximo de %d anos", km, annualKm, maxPlanMonths/12)), nil
+		}
+		if months == 0 || int(byKm) < months {
+			months = int(byKm)
+		}
+	}
+	if months == 0 {
+		months = 36
+	}
+	months = min(months, maxPlanMonths)
+
+	vehicle, err := s.loadVehicle(ctx, request.GetInt("vehicle_id", 0), request.GetString("brand", ""), request.GetString("model", ""), request.GetString("version", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	services, err := s.maintenanceServices(ctx, vehicle.ModelID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(services) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("sem plano de manutenção cadastrado para %s %s", vehicle.Brand, vehicle.Model)), nil
+	}
+
+	history, err := s.maintenanceHistory(ctx, vehicle.ID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	now := time.Now()
+	result := maintenancePlan{
+		Vehicle:    vehicle.Vehicle,
+		StartKm:    vehicle.Km,
+		AnnualKm:   annualKm,
+		Months:     months,
+		Services:   services,
+		Schedule:   []plannedService{},
+		YearTotals: make([]maintenanceYear, (months+11)/12),
+		History:    history,
+	}
+	for i := range result.YearTotals {
+		result.YearTotals[i].Year = i + 1
+	}
+
+	items := make([]finance.MaintenanceItem, len(services))
+	for i, m := range services {
+		items[i] = m.MaintenanceItem
+		if scarceParts[m.PartsAvailability] {
+			result.Alerts = append(result.Alerts, fmt.Sprintf("peças de %s com disponibilidade %s: pode haver espera e custo maior", m.Name, m.PartsAvailability))
+		}
+	}
+
+	events := finance.PlanMaintenance(items, lastServices(services, history, now), vehicle.Km, annualKm, months)
+	for _, event := range events {
+		m := services[event.Item]
+		result.Schedule = append(result.Schedule, plannedService{
+			Date:    now.AddDate(0, event.Month, 0).Format("2006-01"),
+			Km:      event.Km,
+			Service: m.Name,
+			Parts:   m.Parts,
+			Labour:  m.Labour,
+			Total:   m.Cost,
+			Overdue: event.Month == 0,
+		})
+		year := 0
+		if event.Month > 0 {
+			year = (event.Month - 1) / 12
+		}
+		result.YearTotals[year].Total = finance.Round(result.YearTotals[year].Total + m.Cost)
+		result.Total += m.Cost
+	}
+	result.Total = finance.Round(result.Total)
+	result.MonthlyAverage = finance.Round(result.Total / float64(months))
+
+	resultJSON, _ := json.Marshal(result)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
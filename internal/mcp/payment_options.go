@@ -0,0 +1,243 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"mcp-gemini-go/internal/finance"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type unavailableOption struct {
+	Mode   string `json:"modalidade"`
+	Reason string `json:"motivo"`
+}
+
+// defaultDiscountRate é o custo de oportunidade padrão do cliente, em % ao
+// mês, usado para trazer os fluxos das modalidades a valor presente.
+const defaultDiscountRate = 0.8
+
+type paymentComparison struct {
+	VehiclePrice float64               `json:"valor_veiculo"`
+	DiscountRate float64               `json:"taxa_desconto_mes"`
+	Options      []finance.CostSummary `json:"opcoes"`
+	Unavailable  []unavailableOption   `json:"indisponiveis"`
+	// Cheapest é a de menor custo a valor presente; CheapestNominal, a de
+	// menor custo_total, que soma valores pagos em datas diferentes.
+	Cheapest        string `json:"mais_barata,omitempty"`
+	CheapestNominal string `json:"menor_custo_nominal,omitempty"`
+	Note            string `json:"observacao"`
+}
+
+// ComparePaymentOptions simula CDC, leasing, consórcio e compra à vista para o
+// mesmo veículo e devolve os resumos ordenados do menor para o maior custo a
+// valor presente, descontado à taxa discount_rate.
+func (s *Server) ComparePaymentOptions(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	vehiclePrice, err := request.RequireFloat("vehicle_price")
+	if err != nil || vehiclePrice <= 0 {
+		return mcp.NewToolResultError("parâmetro 'vehicle_price' é obrigatório e deve ser maior que zero"), nil
+	}
+
+	downPayment, _, errResult := entryFrom(request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	if downPayment >= vehiclePrice {
+		return mcp.NewToolResultError("a entrada deve ficar entre zero e o valor do veículo"), nil
+	}
+
+	vrgMode, err := finance.ParseVRGMode(request.GetString("vrg_mode", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	bank := request.GetString("bank", "")
+	model := request.GetString("model", "")
+	installments := 0
+	if _, ok := request.GetArguments()["installments"]; ok {
+		installments, errResult = termFrom(request)
+		if errResult != nil {
+			return errResult, nil
+		}
+	}
+
+	discountRate := request.GetFloat("discount_rate", defaultDiscountRate)
+	if discountRate < 0 || discountRate >= 100 {
+		return mcp.NewToolResultError("a taxa de desconto deve ficar entre 0 e 100% ao mês"), nil
+	}
+
+	result := paymentComparison{
+		VehiclePrice: vehiclePrice,
+		DiscountRate: discountRate,
+		Options:      []finance.CostSummary{},
+		Unavailable:  []unavailableOption{},
+		Note: fmt.Sprintf("custo_total soma valores pagos em meses diferentes e não compara modalidades; "+
+			"a ordem e mais_barata usam custo_valor_presente, com parcelas e recebimento do veículo descontados a %.2f%% ao mês", discountRate),
+	}
+	add := func(mode string, summary finance.CostSummary, err error) {
+		if err != nil {
+			result.Unavailable = append(result.Unavailable, unavailableOption{Mode: mode, Reason: err.Error()})
+			return
+		}
+		summary.Discount(discountRate / 100)
+		result.Options = append(result.Options, summary)
+	}
+
+	// CDC com o IOF financiado, para comparar com o leasing, que é isento.
+	summary, err := s.simulateCDC(ctx, vehiclePrice, downPayment, bank, model, installments)
+	add(finance.ModeCDC, summary, err)
+
+	summary, err = s.simulateLeasing(ctx, vehiclePrice, downPayment, bank, installments,
+		vehiclePrice*request.GetFloat("vrg_percent", 0)/100, vrgMode)
+	add(finance.ModeLeasing, summary, err)
+
+	summary, err = s.simulateConsorcio(ctx, request, vehiclePrice, downPayment, bank)
+	add(finance.ModeConsorcio, summary, err)
+
+	summary, err = s.simulateCash(ctx, vehiclePrice, model, request.GetFloat("cash_discount", 0))
+	add(finance.ModeCash, summary, err)
+
+	sort.SliceStable(result.Options, func(i, j int) bool {
+		return result.Options[i].TotalCost < result.Options[j].TotalCost
+	})
+	if len(result.Options) > 0 {
+		result.CheapestNominal = result.Options[0].Mode
+	}
+	sort.SliceStable(result.Options, func(i, j int) bool {
+		return result.Options[i].PresentCost < result.Options[j].PresentCost
+	})
+	if len(result.Options) > 0 {
+		result.Cheapest = result.Options[0].Mode
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+func (s *Server) simulateCDC(ctx context.Context, price, downPayment float64, bank, model string, installments int) (finance.CostSummary, error) {
+	offer, err := s.lookupOffer(ctx, bank, finance.ModeCDC)
+	if err != nil {
+		return finance.CostSummary{}, err
+	}
+	installments, err = offerTerm(offer, installments)
+	if err != nil {
+		return finance.CostSummary{}, err
+	}
+
+	campaign, err := s.campaignRate(ctx, model, offer.MonthlyRate)
+	if err != nil {
+		return finance.CostSummary{}, err
+	}
+	if campaign != nil {
+		offer.MonthlyRate = *campaign.SpecialRate / 100
+	}
+
+	cet, err := finance.ComputeCET(finance.Price, price-downPayment, offer.MonthlyRate, installments, finance.Fees{Financed: true})
+	if err != nil {
+		return finance.CostSummary{}, err
+	}
+
+	summary := finance.CDCSummary(price, downPayment, cet.Schedule)
+	summary.Provider = offer.Bank
+	summary.Notes = append(summary.Notes, fmt.Sprintf("taxa de %.2f%% ao mês com IOF de R$ %.2f incluído no valor financiado", offer.MonthlyRate*100, cet.IOF))
+	if campaign != nil {
+		summary.Notes = append(summary.Notes, fmt.Sprintf("taxa especial da campanha '%s' (%s)", campaign.Name, campaign.Description))
+	}
+	return summary, nil
+}
+
+func (s *Server) simulateLeasing(ctx context.Context, price, downPayment float64, bank string, installments int, vrg float64, mode finance.VRGMode) (finance.CostSummary, error) {
+	offer, err := s.lookupOffer(ctx, bank, finance.ModeLeasing)
+	if err != nil {
+		return finance.CostSummary{}, err
+	}
+	installments, err = offerTerm(offer, installments)
+	if err != nil {
+		return finance.CostSummary{}, err
+	}
+
+	summary, err := finance.Leasing(price, downPayment, offer.MonthlyRate, installments, vrg, mode)
+	if err != nil {
+		return finance.CostSummary{}, err
+	}
+	summary.Provider = offer.Bank
+	summary.Notes = append(summary.Notes, fmt.Sprintf("taxa de %.2f%% ao mês com VRG de R$ %.2f (%s)", offer.MonthlyRate*100, vrg, mode))
+	return summary, nil
+}
+
+// simulateConsorcio simula a cota do consórcio com a entrada completando a carta
+// de crédito na contemplação. A taxa de administração não tem padrão: a taxa
+// cadastrada em financiamentos é de juros e não vale para o grupo. Sem
+// contemplation_month, o cenário é a contemplação no meio do prazo.
+func (s *Server) simulateConsorcio(ctx context.Context, request mcp.CallToolRequest, price, downPayment float64, bank string) (finance.CostSummary, error) {
+	if _, ok := request.GetArguments()["admin_fee"]; !ok {
+		return finance.CostSummary{}, fmt.Errorf("informe admin_fee, a taxa de administração total do grupo, para simular o consórcio")
+	}
+	offer, err := s.lookupOffer(ctx, bank, finance.ModeConsorcio)
+	if err != nil {
+		return finance.CostSummary{}, err
+	}
+	months, err := offerTerm(offer, 0)
+	if err != nil {
+		return finance.CostSummary{}, err
+	}
+
+	adminFee := request.GetFloat("admin_fee", 0) / 100
+	reserveFund := request.GetFloat("reserve_fund", 0) / 100
+	contemplation := request.GetInt("contemplation_month", int(math.Ceil(float64(months)/2)))
+
+	summary, err := finance.Consorcio(price, downPayment, adminFee, reserveFund, months, contemplation)
+	if err != nil {
+		return finance.CostSummary{}, err
+	}
+	summary.Provider = offer.Bank
+	summary.Notes = append(summary.Notes, fmt.Sprintf("taxa de administração de %.2f%% e fundo de reserva de %.2f%% sobre a carta de crédito", adminFee*100, reserveFund*100))
+	return summary, nil
+}
+
+// offerTerm devolve o prazo pedido ou, sem ele, o cadastrado na condição do
+// banco, que também precisa respeitar os limites de finance.CheckTerm.
+func offerTerm(offer financingOffer, requested int) (int, error) {
+	if requested > 0 {
+		return requested, nil
+	}
+	if err := finance.CheckTerm(offer.Installments); err != nil {
+		return 0, fmt.Errorf("prazo cadastrado de %d parcelas para %s: %w", offer.Installments, offer.Bank, err)
+	}
+	return offer.Installments, nil
+}
+
+// simulateCash aplica o maior desconto entre as campanhas vigentes do modelo e,
+// em seguida, o desconto negociado em porcentagem.
+func (s *Server) simulateCash(ctx context.Context, price float64, model string, extraDiscount float64) (finance.CostSummary, error) {
+	if extraDiscount < 0 || extraDiscount >= 100 {
+		return finance.CostSummary{}, fmt.Errorf("o desconto à vista deve ficar entre 0 e 100%%")
+	}
+
+	var notes []string
+	discountedPrice := price
+	if model != "" {
+		campaigns, err := s.loadActiveCampaigns(ctx, model)
+		if err != nil {
+			return finance.CostSummary{}, err
+		}
+		if campaign := bestDiscount(campaigns, price); campaign != nil {
+			discountedPrice = campaign.DiscountedPrice(price)
+			notes = append(notes, fmt.Sprintf("campanha '%s' aplicada: R$ %.2f de desconto", campaign.Name, price-discountedPrice))
+		} else {
+			notes = append(notes, fmt.Sprintf("nenhuma campanha de desconto vigente para %s", model))
+		}
+	}
+
+	paid := discountedPrice * (1 - extraDiscount/100)
+	summary := finance.Cash(price, 0, price-paid)
+	if extraDiscount > 0 {
+		notes = append(notes, fmt.Sprintf("desconto negociado de %.2f%%", extraDiscount))
+	}
+	summary.Notes = notes
+	return summary, nil
+}
@@ -0,0 +1,461 @@
+package mcp
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ColumnAccess define como uma coluna aparece para um perfil.
+type ColumnAccess string
+
+const (
+	ColumnVisible   ColumnAccess = "visible"
+	ColumnHidden    ColumnAccess = "hidden"
+	ColumnMasked    ColumnAccess = "masked"
+	ColumnAggregate ColumnAccess = "aggregate"
+)
+
+const rejectPolicy = "acesso_negado"
+
+// aggregateFunctions são as únicas funções que podem receber colunas "aggregate".
+// MIN e MAX ficam de fora porque devolvem o valor de uma linha.
+var aggregateFunctions = map[string]bool{"AVG": true, "SUM": true, "COUNT": true}
+
+//go:embed sql_policy.json
+var defaultPolicy []byte
+
+// TablePolicy restringe as colunas de uma tabela; Hidden esconde a tabela
+// inteira, mesmo quando o perfil libera "*".
+type TablePolicy struct {
+	Hidden  bool                    `json:"hidden,omitempty"`
+	Columns map[string]ColumnAccess `json:"columns"`
+}
+
+// RolePolicy lista as tabelas visíveis para o perfil; "*" libera todas as
+// tabelas não listadas, sem restrição de colunas, e as marcadas com hidden
+// continuam de fora.
+type RolePolicy struct {
+	Tables map[string]TablePolicy `json:"tables"`
+}
+
+// Policy associa clientes MCP a perfis e perfis às tabelas e colunas que
+// podem ver em get_schema e execute_sql.
+type Policy struct {
+	DefaultRole string                `json:"default_role"`
+	Clients     map[string]string     `json:"clients"`
+	Roles       map[string]RolePolicy `json:"roles"`
+}
+
+// LoadPolicy lê a política de path ou, se vazio, usa a política embutida.
+func LoadPolicy(path string) (*Policy, error) {
+	data := defaultPolicy
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler política de acesso: %w", err)
+		}
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("erro ao interpretar política de acesso: %w", err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("política de acesso inválida: %w", err)
+	}
+	return &policy, nil
+}
+
+func (p *Policy) validate() error {
+	if _, ok := p.Roles[p.DefaultRole]; !ok {
+		return fmt.Errorf("perfil padrão '%s' não está definido", p.DefaultRole)
+	}
+	for client, role := range p.Clients {
+		if _, ok := p.Roles[role]; !ok {
+			return fmt.Errorf("cliente '%s' usa perfil inexistente '%s'", client, role)
+		}
+	}
+	for role, rolePolicy := range p.Roles {
+		for table, tablePolicy := range rolePolicy.Tables {
+			for column, access := range tablePolicy.Columns {
+				switch access {
+				case ColumnVisible, ColumnHidden, ColumnMasked, ColumnAggregate:
+				default:
+					return fmt.Errorf("acesso '%s' inválido em %s.%s.%s", access, role, table, column)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// RoleFor devolve o perfil do cliente, ou o perfil padrão.
+func (p *Policy) RoleFor(client string) (string, RolePolicy) {
+	role, ok := p.Clients[client]
+	if !ok {
+		role = p.DefaultRole
+	}
+	return role, p.Roles[role]
+}
+
+func (r RolePolicy) table(name string) (TablePolicy, bool) {
+	if table, ok := r.Tables[name]; ok {
+		return table, !table.Hidden
+	}
+	_, all := r.Tables["*"]
+	return TablePolicy{}, all
+}
+
+func (t TablePolicy) access(column string) ColumnAccess {
+	if access, ok := t.Columns[column]; ok {
+		return access
+	}
+	return ColumnVisible
+}
+
+// VisibleSchema filtra o schema do banco (tabela -> colunas com tipo) para o
+// perfil, omitindo colunas ocultas e anotando as mascaradas e agregadas.
+func (r RolePolicy) VisibleSchema(columns map[string][]schemaColumn) map[string][]string {
+	schema := make(map[string][]string)
+	for table, tableColumns := range columns {
+		tablePolicy, ok := r.table(table)
+		if !ok {
+			continue
+		}
+		visible := make([]string, 0, len(tableColumns))
+		for _, column := range tableColumns {
+			switch tablePolicy.access(column.Name) {
+			case ColumnHidden:
+				continue
+			case ColumnMasked:
+				visible = append(visible, fmt.Sprintf("%s (%s, mascarado)", column.Name, column.Type))
+			case ColumnAggregate:
+				visible = append(visible, fmt.Sprintf("%s (%s, apenas em AVG, SUM ou COUNT sobre a tabela inteira)", column.Name, column.Type))
+			default:
+				visible = append(visible, fmt.Sprintf("%s (%s)", column.Name, column.Type))
+			}
+		}
+		schema[table] = visible
+	}
+	return schema
+}
+
+type schemaColumn struct {
+	Name string
+	Type string
+}
+
+type sqlToken struct {
+	text  string
+	ident bool
+}
+
+// Rewrite confere a consulta contra a política e devolve a versão a executar:
+// cada tabela com restrições é sombreada por uma CTE de mesmo nome, que omite
+// colunas ocultas e mascara as demais, de modo que nem aliases nem SELECT *
+// tragam os valores originais. Colunas "aggregate" chegam à consulta já
+// substituídas pela média da tabela e, no texto, só podem aparecer dentro de
+// AVG, SUM ou COUNT, sem filtros, junções, agrupamentos nem funções de janela.
+// Só funções de allowedFunctions podem ser chamadas, para que nenhuma consulta
+// em texto seja executada contra as tabelas originais.
+func (r RolePolicy) Rewrite(query string, columns map[string][]schemaColumn) (string, error) {
+	code, err := stripLiterals(query)
+	if err != nil {
+		return "", err
+	}
+	tokens := tokenizeSQL(code)
+	if err := checkFunctions(tokens); err != nil {
+		return "", err
+	}
+
+	referenced := make(map[string]bool)
+	used := make(map[string]bool)
+	for _, token := range tokens {
+		if !token.ident {
+			continue
+		}
+		name := strings.ToLower(token.text)
+		used[name] = true
+		if name == "public" || name == "information_schema" || strings.HasPrefix(name, "pg_") {
+			return "", denied(fmt.Sprintf("o acesso a %s não é permitido", name),
+				"consulte apenas as tabelas listadas em get_schema, sem prefixo de schema")
+		}
+		if _, isTable := columns[name]; !isTable {
+			continue
+		}
+		if _, ok := r.table(name); !ok {
+			return "", denied(fmt.Sprintf("a tabela %s não está disponível para este perfil", name),
+				"consulte get_schema para ver as tabelas disponíveis")
+		}
+		referenced[name] = true
+	}
+
+	hidden := make(map[string]string)
+	aggregate := make(map[string]bool)
+	visible := make(map[string]bool)
+	for table := range referenced {
+		tablePolicy, _ := r.table(table)
+		for _, column := range columns[table] {
+			switch tablePolicy.access(column.Name) {
+			case ColumnHidden:
+				hidden[column.Name] = table
+			case ColumnAggregate:
+				aggregate[column.Name] = true
+			default:
+				visible[column.Name] = true
+			}
+		}
+	}
+
+	for column, table := range hidden {
+		if used[column] && !visible[column] && !aggregate[column] {
+			return "", denied(fmt.Sprintf("a coluna %s.%s não está disponível para este perfil", table, column),
+				"remova a coluna da consulta; get_schema lista as colunas disponíveis")
+		}
+	}
+	if err := checkAggregateColumns(tokens, aggregate, visible); err != nil {
+		return "", err
+	}
+
+	// Todas as tabelas com restrição são sombreadas, mesmo as que não foram
+	// reconhecidas na consulta, para que um erro de análise não exponha dados.
+	var ctes []string
+	tables := make([]string, 0, len(columns))
+	for table := range columns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		if cte, restricted := r.tableCTE(table, columns[table], used); restricted {
+			ctes = append(ctes, cte)
+		}
+	}
+	if len(ctes) == 0 {
+		return query, nil
+	}
+
+	prefix := strings.Join(ctes, ", ")
+	if match := withClause.FindStringIndex(query); match != nil {
+		head := strings.ToUpper(query[match[0]:match[1]])
+		keyword := "WITH "
+		if strings.Contains(head, "RECURSIVE") {
+			keyword = "WITH RECURSIVE "
+		}
+		return keyword + prefix + ", " + query[match[1]:], nil
+	}
+	return "WITH " + prefix + " " + query, nil
+}
+
+var withClause = regexp.MustCompile(`(?is)^(\s|--[^\n]*\n|/\*.*?\*/)*with(\s+recursive)?\s`)
+
+// tableCTE monta a CTE que substitui a tabela; tabelas fora do perfil viram uma
+// CTE vazia. Colunas agregadas só entram quando a consulta as menciona e trazem
+// a média da tabela no lugar do valor da linha.
+func (r RolePolicy) tableCTE(table string, tableColumns []schemaColumn, used map[string]bool) (string, bool) {
+	tablePolicy, ok := r.table(table)
+	if !ok {
+		return fmt.Sprintf("%s AS (SELECT NULL AS indisponivel WHERE false)", quoteIdent(table)), true
+	}
+	restricted := false
+	var selected []string
+	for _, column := range tableColumns {
+		name := quoteIdent(column.Name)
+		switch tablePolicy.access(column.Name) {
+		case ColumnHidden:
+			restricted = true
+		case ColumnMasked:
+			restricted = true
+			selected = append(selected, fmt.Sprintf(
+				"regexp_replace(left(%[1]s::text, -2), '[[:alnum:]]', '*', 'g') || right(%[1]s::text, 2) AS %[1]s", name))
+		case ColumnAggregate:
+			// Toda linha recebe a média da tabela, de modo que AVG, SUM e COUNT
+			// sobre a tabela inteira continuam exatos, mas nenhuma combinação de
+			// filtros recupera o valor de uma pessoa.
+			restricted = true
+			if used[column.Name] {
+				selected = append(selected, fmt.Sprintf(
+					"CASE WHEN %[1]s IS NULL THEN NULL ELSE AVG(%[1]s) OVER () END AS %[1]s", name))
+			}
+		default:
+			selected = append(selected, name)
+		}
+	}
+	if len(selected) == 0 {
+		selected = append(selected, "NULL AS sem_colunas")
+	}
+	return fmt.Sprintf("%s AS (SELECT %s FROM public.%s)", quoteIdent(table), strings.Join(selected, ", "), quoteIdent(table)), restricted
+}
+
+// aggregateFilters são as cláusulas que restringiriam a agregação a parte da
+// tabela; com elas, uma agregação poderia isolar a linha de uma pessoa.
+var aggregateFilters = map[string]bool{
+	"WHERE": true, "JOIN": true, "HAVING": true, "FILTER": true, "OVER": true, "PARTITION": true,
+}
+
+func checkAggregateColumns(tokens []sqlToken, aggregate, visible map[string]bool) error {
+	if !mentionsAny(tokens, aggregate, visible) {
+		return nil
+	}
+
+	var calls []string
+	for i, token := range tokens {
+		upper := strings.ToUpper(token.text)
+		switch {
+		case token.text == "(":
+			function := ""
+			if i > 0 && tokens[i-1].ident {
+				function = strings.ToUpper(tokens[i-1].text)
+			}
+			calls = append(calls, function)
+		case token.text == ")":
+			if len(calls) > 0 {
+				calls = calls[:len(calls)-1]
+			}
+		case token.text == "*":
+			insideCount := i > 0 && tokens[i-1].text == "(" && len(calls) > 0 && calls[len(calls)-1] == "COUNT"
+			if !insideCount && i > 0 && isStarPosition(tokens[i-1]) {
+				return denied("SELECT * não é permitido em tabelas com colunas restritas a agregações",
+					"liste as colunas desejadas explicitamente")
+			}
+		case token.ident && aggregate[strings.ToLower(token.text)] && !visible[strings.ToLower(token.text)]:
+			if !insideAggregate(calls) {
+				return denied(fmt.Sprintf("a coluna %s só pode ser usada dentro de AVG, SUM ou COUNT", strings.ToLower(token.text)),
+					"use, por exemplo, AVG(coluna) sobre a tabela inteira")
+			}
+		case token.ident && (aggregateFilters[upper] || (upper == "GROUP" && i+1 < len(tokens) && strings.ToUpper(tokens[i+1].text) == "BY")):
+			return denied(fmt.Sprintf("%s não é permitido em consultas com colunas restritas a agregações", upper),
+				"essas colunas só podem ser agregadas sobre a tabela inteira, sem WHERE, JOIN, GROUP BY, HAVING, FILTER ou funções de janela")
+		}
+	}
+	return nil
+}
+
+// sqlKeywordsBeforeParen são palavras-chave que podem vir antes de "(" sem
+// serem chamadas de função: subconsultas, listas e cláusulas.
+var sqlKeywordsBeforeParen = map[string]bool{
+	"SELECT": true, "FROM": true, "JOIN": true, "LATERAL": true, "ON": true, "USING": true,
+	"WHERE": true, "AND": true, "OR": true, "NOT": true, "IN": true, "EXISTS": true,
+	"ANY": true, "ALL": true, "SOME": true, "AS": true, "WITH": true, "RECURSIVE": true,
+	"OVER": true, "FILTER": true, "WITHIN": true, "GROUP": true, "BY": true, "HAVING": true,
+	"VALUES": true, "ROW": true, "ARRAY": true, "CASE": true, "WHEN": true, "THEN": true,
+	"ELSE": true, "IS": true, "LIKE": true, "ILIKE": true, "BETWEEN": true, "DISTINCT": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "LIMIT": true, "OFFSET": true,
+	"ORDER": true, "ASC": true, "DESC": true, "LEFT": true, "RIGHT": true,
+}
+
+// allowedFunctions são as funções liberadas em execute_sql. A lista é fechada
+// porque funções como query_to_xml ou table_to_xml executam SQL recebido em
+// texto, fora do alcance das CTEs que aplicam a política.
+var allowedFunctions = map[string]bool{
+	// agregações e janelas
+	"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true,
+	"STDDEV": true, "STDDEV_POP": true, "STDDEV_SAMP": true, "VARIANCE": true,
+	"STRING_AGG": true, "ARRAY_AGG": true, "BOOL_AND": true, "BOOL_OR": true, "EVERY": true,
+	"PERCENTILE_CONT": true, "PERCENTILE_DISC": true, "MODE": true,
+	"ROW_NUMBER": true, "RANK": true, "DENSE_RANK": true, "NTILE": true,
+	"LAG": true, "LEAD": true, "FIRST_VALUE": true, "LAST_VALUE": true,
+	// matemáticas
+	"ROUND": true, "TRUNC": true, "CEIL": true, "CEILING": true, "FLOOR": true,
+	"ABS": true, "POWER": true, "SQRT": true, "MOD": true, "SIGN": true,
+	"GREATEST": true, "LEAST": true,
+	// condicionais e conversões
+	"COALESCE": true, "NULLIF": true, "CAST": true,
+	"NUMERIC": true, "DECIMAL": true, "VARCHAR": true, "CHAR": true, "CHARACTER": true,
+	"TIMESTAMP": true, "TIME": true,
+	// texto
+	"LOWER": true, "UPPER": true, "INITCAP": true, "LENGTH": true, "CHAR_LENGTH": true,
+	"TRIM": true, "LTRIM": true, "RTRIM": true, "BTRIM": true, "SUBSTRING": true, "SUBSTR": true,
+	"POSITION": true, "STRPOS": true, "REPLACE": true, "CONCAT": true, "CONCAT_WS": true,
+	"SPLIT_PART": true, "LPAD": true, "RPAD": true, "TO_CHAR": true, "TO_DATE": true, "TO_NUMBER": true,
+	// datas
+	"EXTRACT": true, "DATE_PART": true, "DATE_TRUNC": true, "AGE": true, "NOW": true,
+	"MAKE_DATE": true, "MAKE_INTERVAL": true,
+	// listas
+	"UNNEST": true, "ARRAY_LENGTH": true,
+}
+
+// checkFunctions recusa chamadas de funções fora de allowedFunctions.
+func checkFunctions(tokens []sqlToken) error {
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i].text != "(" || !tokens[i-1].ident {
+			continue
+		}
+		name := strings.ToUpper(tokens[i-1].text)
+		if allowedFunctions[name] || sqlKeywordsBeforeParen[name] {
+			continue
+		}
+		// Lista de colunas de um alias: "AS t(a, b)".
+		if i >= 2 && strings.ToUpper(tokens[i-2].text) == "AS" {
+			continue
+		}
+		return denied(fmt.Sprintf("a função %s não é permitida", strings.ToLower(name)),
+			"use funções de agregação, matemáticas, de texto ou de data comuns; funções que executam SQL em texto são bloqueadas")
+	}
+	return nil
+}
+
+func isStarPosition(previous sqlToken) bool {
+	if previous.text == "," || previous.text == "." {
+		return true
+	}
+	upper := strings.ToUpper(previous.text)
+	return upper == "SELECT" || upper == "DISTINCT" || upper == "ALL"
+}
+
+func insideAggregate(calls []string) bool {
+	for _, function := range calls {
+		if aggregateFunctions[function] {
+			return true
+		}
+	}
+	return false
+}
+
+func mentionsAny(tokens []sqlToken, aggregate, visible map[string]bool) bool {
+	for _, token := range tokens {
+		name := strings.ToLower(token.text)
+		if token.ident && aggregate[name] && !visible[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenizeSQL separa identificadores, palavras-chave e os símbolos relevantes
+// para a política; espera o texto já sem literais.
+func tokenizeSQL(code string) []sqlToken {
+	var tokens []sqlToken
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			j := i + 1
+			for j < len(code) && (code[j] == '_' || code[j] == '$' || (code[j] >= 'a' && code[j] <= 'z') ||
+				(code[j] >= 'A' && code[j] <= 'Z') || (code[j] >= '0' && code[j] <= '9')) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: code[i:j], ident: true})
+			i = j
+		case c == '(' || c == ')' || c == '*' || c == ',' || c == '.':
+			tokens = append(tokens, sqlToken{text: string(c)})
+			i++
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func denied(reason, hint string) error {
+	return queryRejection{Code: rejectPolicy, Reason: reason, Hint: hint}
+}
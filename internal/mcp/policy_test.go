@@ -0,0 +1,156 @@
+package mcp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testSchema = map[string][]schemaColumn{
+	"clientes": {
+		{"id_clientes", "integer"},
+		{"nome", "character varying"},
+		{"cpf", "character varying"},
+		{"data_nascimento", "date"},
+		{"renda_mensal", "numeric"},
+		{"score_credito", "integer"},
+	},
+	"vendedores": {
+		{"id_vendedores", "integer"},
+		{"nome", "character varying"},
+		{"meta_mensal", "numeric"},
+	},
+	"veiculos": {
+		{"id_veiculos", "integer"},
+		{"preco_venda", "numeric"},
+	},
+	"conversas": {
+		{"id_conversa", "character varying"},
+	},
+}
+
+func testRole(t *testing.T, client string) RolePolicy {
+	t.Helper()
+	policy, err := LoadPolicy("")
+	if err != nil {
+		t.Fatalf("LoadPolicy: %v", err)
+	}
+	_, role := policy.RoleFor(client)
+	return role
+}
+
+func TestRewriteMasksAndHidesColumns(t *testing.T) {
+	role := testRole(t, "chat")
+
+	query, err := role.Rewrite("SELECT cpf FROM clientes", testSchema)
+	if err != nil {
+		t.Fatalf("Rewrite: %v", err)
+	}
+	if !strings.Contains(query, `regexp_replace(left("cpf"::text, -2)`) {
+		t.Errorf("cpf deveria ser mascarado: %s", query)
+	}
+	clientes := query[:strings.Index(query, `FROM public."clientes"`)]
+	if strings.Contains(clientes, `"nome"`) || strings.Contains(clientes, `"data_nascimento"`) {
+		t.Errorf("colunas ocultas não podem entrar na CTE: %s", query)
+	}
+
+	schema := role.VisibleSchema(testSchema)
+	for _, column := range schema["clientes"] {
+		if strings.HasPrefix(column, "nome ") || strings.HasPrefix(column, "data_nascimento ") {
+			t.Errorf("coluna oculta no schema: %s", column)
+		}
+	}
+	if _, ok := schema["conversas"]; ok {
+		t.Error("conversas não está liberada para o assistente")
+	}
+}
+
+func TestRewriteRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		client string
+		query  string
+	}{
+		{"coluna oculta", "chat", "SELECT nome FROM clientes"},
+		{"coluna oculta com alias", "chat", "SELECT c.data_nascimento FROM clientes c"},
+		{"tabela fora do perfil", "chat", "SELECT id_conversa FROM conversas"},
+		{"tabela oculta mesmo com *", "gerencia", "SELECT id_conversa FROM conversas"},
+		{"agregada fora de agregação", "chat", "SELECT renda_mensal FROM clientes"},
+		{"agregada com MAX", "chat", "SELECT MAX(renda_mensal) FROM clientes"},
+		{"SELECT * com agregada", "chat", "SELECT *, AVG(renda_mensal) FROM clientes"},
+		{"média de um cliente", "chat", "SELECT AVG(renda_mensal) FROM clientes WHERE id_clientes = 5"},
+		{"busca binária no score", "chat",
+			"SELECT SUM(CASE WHEN score_credito > 700 THEN 1 ELSE 0 END) FROM clientes WHERE id_clientes = 5"},
+		{"meta de um vendedor", "chat", "SELECT SUM(meta_mensal) FROM vendedores WHERE id_vendedores = 3"},
+		{"filtro em subconsulta", "chat",
+			"SELECT (SELECT AVG(renda_mensal) FROM clientes c WHERE c.id_clientes = 5) AS renda"},
+		{"FILTER", "chat", "SELECT AVG(renda_mensal) FILTER (WHERE id_clientes = 5) FROM clientes"},
+		{"JOIN", "chat", "SELECT AVG(c.renda_mensal) FROM clientes c JOIN veiculos v ON v.id_veiculos = c.id_clientes"},
+		{"GROUP BY", "chat", "SELECT id_clientes, AVG(renda_mensal) FROM clientes GROUP BY id_clientes"},
+		{"função de janela", "chat", "SELECT AVG(renda_mensal) OVER (PARTITION BY id_clientes) FROM clientes"},
+		{"query_to_xml", "chat", "SELECT query_to_xml('SELECT cpf, renda_mensal FROM public.clientes', true, false, '')"},
+		{"query_to_xml para o gerente", "gerencia", "SELECT query_to_xml('SELECT cpf FROM public.clientes', true, false, '')"},
+		{"table_to_xml", "chat", "SELECT table_to_xml('clientes', true, false, '')"},
+		{"cursor_to_xml", "chat", "SELECT cursor_to_xml('c', 10, true, false, '')"},
+		{"schema_to_xml", "gerencia", "SELECT schema_to_xml('public', true, false, '')"},
+		{"database_to_xml", "chat", "SELECT database_to_xml(true, false, '')"},
+		{"query_to_xml entre aspas", "chat", `SELECT "query_to_xml"('SELECT cpf FROM clientes', true, false, '')`},
+		{"dblink", "chat", "SELECT * FROM dblink('dbname=x', 'SELECT cpf FROM clientes') AS t(cpf text)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := testRole(t, tt.client).Rewrite(tt.query, testSchema)
+			var rejection queryRejection
+			if !errors.As(err, &rejection) || rejection.Code != rejectPolicy {
+				t.Fatalf("consulta deveria ser negada, reescrita para: %s (erro %v)", query, err)
+			}
+		})
+	}
+}
+
+func TestRewriteAllowsCommonFunctions(t *testing.T) {
+	role := testRole(t, "chat")
+	for _, query := range []string{
+		"SELECT ROUND(AVG(preco_venda), 2), COUNT(*) FROM veiculos",
+		"SELECT id_veiculos, COALESCE(preco_venda, 0)::numeric(12, 2) FROM veiculos WHERE preco_venda IN (SELECT MAX(preco_venda) FROM veiculos)",
+		"SELECT LOWER(nome), EXTRACT(YEAR FROM NOW()) FROM vendedores",
+		"SELECT x.n FROM (SELECT id_veiculos AS n FROM veiculos) AS x(n)",
+	} {
+		if _, err := role.Rewrite(query, testSchema); err != nil {
+			t.Errorf("Rewrite(%q): %v", query, err)
+		}
+	}
+}
+
+func TestRewriteAggregatesWholeTable(t *testing.T) {
+	role := testRole(t, "chat")
+
+	query, err := role.Rewrite("SELECT AVG(renda_mensal), COUNT(score_credito) FROM clientes", testSchema)
+	if err != nil {
+		t.Fatalf("Rewrite: %v", err)
+	}
+	for _, column := range []string{"renda_mensal", "score_credito"} {
+		want := `CASE WHEN "` + column + `" IS NULL THEN NULL ELSE AVG("` + column + `") OVER () END AS "` + column + `"`
+		if !strings.Contains(query, want) {
+			t.Errorf("%s deveria trazer a média da tabela: %s", column, query)
+		}
+	}
+	if strings.Contains(query, `"meta_mensal"`) {
+		t.Errorf("colunas agregadas não mencionadas não entram na CTE: %s", query)
+	}
+}
+
+func TestRewriteManagerSeesAllButHiddenTables(t *testing.T) {
+	role := testRole(t, "gerencia")
+
+	query, err := role.Rewrite("SELECT nome, renda_mensal, preco_venda FROM clientes, veiculos WHERE id_clientes = 5", testSchema)
+	if err != nil {
+		t.Fatalf("Rewrite: %v", err)
+	}
+	if !strings.Contains(query, `regexp_replace(left("cpf"::text, -2)`) {
+		t.Errorf("cpf deveria continuar mascarado para o gerente: %s", query)
+	}
+	if !strings.Contains(query, `"conversas" AS (SELECT NULL AS indisponivel WHERE false)`) {
+		t.Errorf("conversas deveria ser sombreada por uma CTE vazia: %s", query)
+	}
+}
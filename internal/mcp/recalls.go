@@ -0,0 +1,181 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// recallResolved é o status_solucao de um recall encerrado; Pendente e
+// Em_Andamento contam como abertos.
+const recallResolved = "Resolvido"
+
+// alertSeverities são as gravidades que geram alerta quando não resolvidas.
+var alertSeverities = []string{"Critica", "Alta"}
+
+// recallAlert descreve o recall aberto mais grave, ou devolve vazio quando
+// nenhum recall aberto tem gravidade de alerta. Os recalls vêm ordenados do
+// mais grave para o menos grave.
+func recallAlert(recalls []Recall) string {
+	for _, r := range recalls {
+		for _, severity := range alertSeverities {
+			if r.Severity == severity && r.Status != recallResolved {
+				return fmt.Sprintf("recall de gravidade %s não resolvido: %s", r.Severity, r.Description)
+			}
+		}
+	}
+	return ""
+}
+
+// loadRecalls lê os recalls e problemas conhecidos do modelo que atingem o
+// ano-modelo informado, ou de todos os anos quando year é zero.
+func (s *Server) loadRecalls(ctx context.Context, modelID, year int) ([]Recall, error) {
+	query := `
+		SELECT
+			COALESCE(numero_recall, ''),
+			tipo_problema,
+			COALESCE(categoria_problema, ''),
+			descricao_problema,
+			COALESCE(gravidade, ''),
+			COALESCE(solucao, ''),
+			COALESCE(status_solucao, 'Pendente'),
+			COALESCE(custo_reparo_estimado, 0),
+			ano_modelo_inicio,
+			ano_modelo_fim,
+			data_comunicado_oficial
+		FROM recalls_problemas
+		WHERE id_modelos = $1
+		AND ($2::int = 0 OR $2 BETWEEN COALESCE(ano_modelo_inicio, $2) AND COALESCE(ano_modelo_fim, $2))
+		ORDER BY
+			CASE gravidade WHEN 'Critica' THEN 0 WHEN 'Alta' THEN 1 WHEN 'Media' THEN 2 ELSE 3 END,
+			data_identificacao DESC NULLS LAST
+	`
+
+	rows, err := s.DB.QueryContext(ctx, query, modelID, year)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar recalls: %w", err)
+	}
+	defer rows.Close()
+
+	var recalls []Recall
+	for rows.Next() {
+		var r Recall
+		var start, end sql.NullInt64
+		var announced sql.NullTime
+		if err := rows.Scan(&r.Number, &r.Type, &r.Category, &r.Description, &r.Severity, &r.Solution,
+			&r.Status, &r.RepairCost, &start, &end, &announced); err != nil {
+			return nil, fmt.Errorf("erro ao ler recall: %w", err)
+		}
+		if start.Valid {
+			year := int(start.Int64)
+			r.ModelYearStart = &year
+		}
+		if end.Valid {
+			year := int(end.Int64)
+			r.ModelYearEnd = &year
+		}
+		if announced.Valid {
+			r.AnnouncedAt = announced.Time.Format("2006-01-02")
+		}
+		recalls = append(recalls, r)
+	}
+	return recalls, rows.Err()
+}
+
+// applyRecallAlerts marca os veículos cujo ano-modelo tem recall Critica ou
+// Alta ainda não resolvido.
+func (s *Server) applyRecallAlerts(ctx context.Context, vehicles []Vehicle) error {
+	if len(vehicles) == 0 {
+		return nil
+	}
+
+	query := `
+		SELECT DISTINCT ON (v.id_veiculos) v.id_veiculos, r.gravidade, r.descricao_problema
+		FROM veiculos v
+		JOIN recalls_problemas r ON r.id_modelos = v.id_modelos
+		WHERE v.id_veiculos = ANY($1)
+		AND v.ano_modelo BETWEEN COALESCE(r.ano_modelo_inicio, v.ano_modelo) AND COALESCE(r.ano_modelo_fim, v.ano_modelo)
+		AND r.gravidade = ANY($2)
+		AND COALESCE(r.status_solucao, 'Pendente') <> $3
+		ORDER BY v.id_veiculos, (r.gravidade = 'Critica') DESC, r.data_identificacao DESC NULLS LAST
+	`
+
+	ids := make([]int64, len(vehicles))
+	for i, v := range vehicles {
+		ids[i] = int64(v.ID)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, query, pq.Array(ids), pq.Array(alertSeverities), recallResolved)
+	if err != nil {
+		return fmt.Errorf("erro ao buscar recalls dos veículos: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := map[int]string{}
+	for rows.Next() {
+		var id int
+		var r Recall
+		if err := rows.Scan(&id, &r.Severity, &r.Description); err != nil {
+			return fmt.Errorf("erro ao ler recall do veículo: %w", err)
+		}
+		alerts[id] = recallAlert([]Recall{r})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range vehicles {
+		vehicles[i].RecallAlert = alerts[vehicles[i].ID]
+	}
+	return nil
+}
+
+// CheckRecalls lista os recalls abertos e resolvidos de um veículo, ou de um
+// modelo e ano-modelo.
+func (s *Server) CheckRecalls(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	report := RecallReport{Open: []Recall{}, Resolved: []Recall{}}
+	var modelID int
+
+	if id := request.GetInt("vehicle_id", 0); id > 0 {
+		vehicle, err := s.loadVehicle(ctx, id, "", "", "")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		modelID = vehicle.ModelID
+		report.Brand, report.Model, report.Year = vehicle.Brand, vehicle.Model, vehicle.ModelYear
+	} else {
+		model := request.GetString("model", "")
+		if model == "" {
+			return mcp.NewToolResultError("informe vehicle_id ou model"), nil
+		}
+		catalog, err := s.findModel(ctx, request.GetString("brand", ""), model)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if catalog == nil {
+			return mcp.NewToolResultError(fmt.Sprintf("modelo %s não encontrado", model)), nil
+		}
+		modelID = catalog.ID
+		report.Brand, report.Model, report.Year = catalog.Brand, catalog.Model, request.GetInt("year", 0)
+	}
+
+	recalls, err := s.loadRecalls(ctx, modelID, report.Year)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	for _, r := range recalls {
+		if r.Status == recallResolved {
+			report.Resolved = append(report.Resolved, r)
+		} else {
+			report.Open = append(report.Open, r)
+		}
+	}
+	report.Alert = recallAlert(report.Open)
+
+	resultJSON, _ := json.Marshal(report)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
@@ -0,0 +1,28 @@
+package mcp
+
+import "testing"
+
+func TestRecallAlert(t *testing.T) {
+	tests := []struct {
+		name    string
+		recalls []Recall
+		want    string
+	}{
+		{"sem recalls", nil, ""},
+		{"gravidade sem alerta", []Recall{{Severity: "Media", Status: "Pendente", Description: "ruído"}}, ""},
+		{"crítico resolvido", []Recall{{Severity: "Critica", Status: recallResolved, Description: "freio"}}, ""},
+		{"alta pendente", []Recall{{Severity: "Alta", Status: "Pendente", Description: "airbag"}},
+			"recall de gravidade Alta não resolvido: airbag"},
+		{"o primeiro aberto com alerta", []Recall{
+			{Severity: "Critica", Status: recallResolved, Description: "freio"},
+			{Severity: "Baixa", Status: "Pendente", Description: "pintura"},
+			{Severity: "Alta", Status: "Em andamento", Description: "airbag"},
+			{Severity: "Critica", Status: "Pendente", Description: "direção"},
+		}, "recall de gravidade Alta não resolvido: airbag"},
+	}
+	for _, tt := range tests {
+		if got := recallAlert(tt.recalls); got != tt.want {
+			t.Errorf("%s: recallAlert = %q, esperado %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"mcp-gemini-go/internal/finance"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// resaleHorizons são os anos projetados por forecast_resale_value.
+var resaleHorizons = []int{1, 3, 5}
+
+// Faixas de depreciação anual usadas para responder se o modelo desvaloriza
+// muito, em fração ao ano.
+const (
+	lowDepreciation      = 0.10
+	moderateDepreciation = 0.15
+)
+
+// depreciationPoints lê o histórico do modelo como pares de idade e retenção.
+// A idade conta em meses desde janeiro do ano-modelo; referências anteriores
+// a isso contam como um ano.
+func (s *Server) depreciationPoints(ctx context.Context, modelID int) ([]finance.DepreciationPoint, error) {
+	query := `
+		SELECT valor_0km, valor_atual, ano_modelo, mes_referencia
+		FROM historico_valorizacao
+		WHERE id_modelos = $1 AND valor_0km > 0 AND valor_atual > 0
+		ORDER BY mes_referencia ASC
+	`
+
+	rows, err := s.DB.QueryContext(ctx, query, modelID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar histórico de valorização: %w", err)
+	}
+	defer rows.Close()
+
+	var points []finance.DepreciationPoint
+	for rows.Next() {
+		var newValue, currentValue float64
+		var modelYear int
+		var reference time.Time
+		if err := rows.Scan(&newValue, &currentValue, &modelYear, &reference); err != nil {
+			return nil, fmt.Errorf("erro ao ler histórico de valorização: %w", err)
+		}
+		months := (reference.Year()-modelYear)*12 + int(reference.Month()) - 1
+		if months < 1 {
+			months = 12
+		}
+		points = append(points, finance.DepreciationPoint{
+			AgeYears:  float64(months) / 12,
+			Retention: currentValue / newValue,
+		})
+	}
+	return points, rows.Err()
+}
+
+// depreciationFit ajusta a curva de depreciação do modelo. Sem histórico
+// devolve nil.
+func (s *Server) depreciationFit(ctx context.Context, modelID int) (*finance.DepreciationFit, error) {
+	points, err := s.depreciationPoints(ctx, modelID)
+	if err != nil || len(points) == 0 {
+		return nil, err
+	}
+	fit, err := finance.FitDepreciation(points)
+	if err != nil {
+		return nil, nil
+	}
+	return &fit, nil
+}
+
+// resaleMarket são os indicadores de revenda do registro mais recente do modelo.
+type resaleMarket struct {
+	SaleEase       string `json:"facilidade_venda,omitempty"`
+	Liquidity      string `json:"liquidez_mercado,omitempty"`
+	AverageDays    int    `json:"tempo_medio_venda_dias,omitempty"`
+	RetentionRank  int    `json:"posicao_ranking_retencao,omitempty"`
+	ReferenceMonth string `json:"mes_referencia"`
+}
+
+func (s *Server) resaleMarket(ctx context.Context, modelID, modelYear int) (*resaleMarket, error) {
+	query := `
+		SELECT
+			COALESCE(facilidade_venda, ''),
+			COALESCE(liquidez_mercado, ''),
+			COALESCE(tempo_medio_venda_dias, 0),
+			COALESCE(posicao_ranking_retencao, 0),
+			mes_referencia
+		FROM historico_valorizacao
+		WHERE id_modelos = $1
+		ORDER BY ABS(ano_modelo - $2) ASC, mes_referencia DESC
+		LIMIT 1
+	`
+
+	var market resaleMarket
+	var reference time.Time
+	err := s.DB.QueryRowContext(ctx, query, modelID, modelYear).Scan(
+		&market.SaleEase, &market.Liquidity, &market.AverageDays, &market.RetentionRank, &reference)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar dados de revenda: %w", err)
+	}
+	market.ReferenceMonth = reference.Format("2006-01")
+	return &market, nil
+}
+
+type resaleProjection struct {
+	Years   int     `json:"anos"`
+	Value   float64 `json:"valor_estimado"`
+	Minimum float64 `json:"valor_minimo"`
+	Maximum float64 `json:"valor_maximo"`
+	LossPct float64 `json:"perda_percentual"`
+}
+
+type resaleForecast struct {
+	Vehicle          Vehicle            `json:"veiculo"`
+	BaseValue        float64            `json:"valor_base"`
+	DepreciationRate float64            `json:"depreciacao_anual"`
+	RateMinimum      float64            `json:"depreciacao_anual_minima"`
+	RateMaximum      float64            `json:"depreciacao_anual_maxima"`
+	Level            string             `json:"nivel_depreciacao"`
+	HistoryPoints    int                `json:"pontos_historico"`
+	Source           string             `json:"fonte"`
+	Projections      []resaleProjection `json:"projecoes"`
+	Market           *resaleMarket      `json:"mercado,omitempty"`
+	Notes            []string           `json:"observacoes,omitempty"`
+}
+
+// depreciationLevel classifica a taxa anual para responder "desvaloriza muito?".
+func depreciationLevel(rate float64) string {
+	switch {
+	case rate < lowDepreciation:
+		return "baixa"
+	case rate < moderateDepreciation:
+		return "moderada"
+	default:
+		return "alta"
+	}
+}
+
+// ForecastResaleValue projeta o valor de revenda em 1, 3 e 5 anos pela curva de
+// depreciação do modelo, com a faixa de confiança e a liquidez de mercado.
+func (s *Server) ForecastResaleValue(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	vehicle, err := s.loadVehicle(ctx, request.GetInt("vehicle_id", 0), request.GetString("brand", ""), request.GetString("model", ""), request.GetString("version", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	baseValue := request.GetFloat("price", vehicle.Price)
+	if baseValue <= 0 {
+		return mcp.NewToolResultError("o valor base deve ser maior que zero"), nil
+	}
+
+	result := resaleForecast{
+		Vehicle:     vehicle.Vehicle,
+		BaseValue:   baseValue,
+		Projections: make([]resaleProjection, 0, len(resaleHorizons)),
+	}
+
+	fit, err := s.depreciationFit(ctx, vehicle.ModelID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if fit != nil {
+		result.Source = sourceDatabase
+		result.HistoryPoints = fit.Points
+		if fit.Points < 3 {
+			result.Notes = append(result.Notes, fmt.Sprintf("histórico com %d ponto(s); faixa de confiança ampliada em %.0f pontos percentuais", fit.Points, finance.DefaultRateSpread*100))
+		}
+	} else {
+		fit = &finance.DepreciationFit{
+			Rate:     defaultDepreciationRate,
+			LowRate:  defaultDepreciationRate - finance.DefaultRateSpread,
+			HighRate: defaultDepreciationRate + finance.DefaultRateSpread,
+		}
+		result.Source = sourceEstimate
+		result.Notes = append(result.Notes, fmt.Sprintf("sem histórico de valorização do modelo; depreciação estimada em %.0f%% ao ano", defaultDepreciationRate*100))
+	}
+
+	result.DepreciationRate = finance.Round(fit.Rate * 100)
+	result.RateMinimum = finance.Round(fit.LowRate * 100)
+	result.RateMaximum = finance.Round(fit.HighRate * 100)
+	result.Level = depreciationLevel(fit.Rate)
+
+	for _, years := range resaleHorizons {
+		value, low, high := fit.Project(baseValue, years)
+		result.Projections = append(result.Projections, resaleProjection{
+			Years:   years,
+			Value:   value,
+			Minimum: low,
+			Maximum: high,
+			LossPct: finance.Round((1 - value/baseValue) * 100),
+		})
+	}
+
+	if result.Market, err = s.resaleMarket(ctx, vehicle.ModelID, vehicle.ModelYear); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
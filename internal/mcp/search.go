@@ -0,0 +1,290 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	defaultSearchPageSize = 10
+	maxSearchPageSize     = 50
+)
+
+// safetyColumns são os itens de seguranca_veiculos aceitos no filtro safety.
+// Só nomes desta lista entram no SQL.
+var safetyColumns = []string{
+	"airbags_frontais",
+	"airbags_laterais",
+	"airbags_cortina",
+	"freios_abs",
+	"controle_estabilidade",
+	"controle_tracao",
+	"assistente_partida_rampa",
+	"camera_re",
+	"sensores_estacionamento",
+	"alerta_ponto_cego",
+	"frenagem_autonoma_emergencia",
+	"alarme_fabrica",
+	"trava_eletrica",
+}
+
+// searchSorts mapeia a ordenação pedida para o ORDER BY.
+var searchSorts = map[string]string{
+	"price_asc":        "v.preco_venda ASC",
+	"price_desc":       "v.preco_venda DESC",
+	"power_desc":       "v.potencia_cv DESC NULLS LAST, v.preco_venda ASC",
+	"consumption_desc": "v.consumo_urbano DESC NULLS LAST, v.preco_venda ASC",
+	"year_desc":        "v.ano_modelo DESC, v.preco_venda ASC",
+	"km_asc":           "v.quilometragem ASC NULLS LAST, v.preco_venda ASC",
+	"ncap_desc":        "sv.nota_latin_ncap DESC NULLS LAST, v.preco_venda ASC",
+}
+
+func isSafetyColumn(name string) bool {
+	for _, column := range safetyColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// likeEscaper escapa os curingas de LIKE com a barra invertida, o caractere de
+// escape padrão do PostgreSQL.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// containsPattern monta o padrão de ILIKE que encontra text em qualquer
+// posição, tratando '%' e '_' do texto como caracteres comuns.
+func containsPattern(text string) string {
+	return "%" + likeEscaper.Replace(text) + "%"
+}
+
+// SearchVehicles busca veículos disponíveis por itens de série e opcionais,
+// itens de segurança, nota Latin NCAP e especificações, com ordenação,
+// paginação e o total de resultados.
+func (s *Server) SearchVehicles(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	from := `
+		FROM veiculos v
+		JOIN modelos mo ON v.id_modelos = mo.id_modelos
+		JOIN marcas m ON mo.id_marcas = m.id_marcas
+		LEFT JOIN LATERAL (
+			SELECT *
+			FROM seguranca_veiculos
+			WHERE id_modelos = v.id_modelos
+			ORDER BY data_atualizacao DESC
+			LIMIT 1
+		) sv ON true
+		WHERE v.status_veiculo = 'Disponivel'
+	`
+
+	var queryArgs []interface{}
+	arg := func(value interface{}) string {
+		queryArgs = append(queryArgs, value)
+		return fmt.Sprintf("$%d", len(queryArgs))
+	}
+
+	for _, filter := range []struct {
+		name, condition string
+	}{
+		{"brand", "LOWER(m.marca) = LOWER(%s)"},
+		{"model", "LOWER(mo.modelo) = LOWER(%s)"},
+		{"category", "mo.categoria = %s"},
+		{"fuel_type", "v.tipo_combustivel = %s"},
+		{"type", "v.tipo_veiculo = %s"},
+	} {
+		if value := request.GetString(filter.name, ""); value != "" {
+			from += " AND " + fmt.Sprintf(filter.condition, arg(value))
+		}
+	}
+
+	// Filtros numéricos informados com valor inválido são recusados em vez de
+	// ignorados. Zero só é aceito onde faz sentido (preço mínimo, nota NCAP
+	// mínima e 0 km), e um mínimo zero não restringe nada.
+	args := request.GetArguments()
+	for _, filter := range []struct {
+		name, condition string
+		allowZero       bool
+	}{
+		{"min_price", "v.preco_venda >= %s", true},
+		{"max_price", "v.preco_venda <= %s", false},
+		{"min_power", "v.potencia_cv >= %s", false},
+		{"max_power", "v.potencia_cv <= %s", false},
+		{"min_urban_consumption", "v.consumo_urbano >= %s", false},
+		{"min_highway_consumption", "v.consumo_rodoviario >= %s", false},
+		{"min_year", "v.ano_modelo >= %s", false},
+		{"max_year", "v.ano_modelo <= %s", false},
+		{"max_km", "COALESCE(v.quilometragem, 0) <= %s", true},
+		{"min_ncap", "sv.nota_latin_ncap >= %s", true},
+	} {
+		if _, ok := args[filter.name]; !ok {
+			continue
+		}
+		value := request.GetFloat(filter.name, -1)
+		if value < 0 || (value == 0 && !filter.allowZero) {
+			return mcp.NewToolResultError(fmt.Sprintf("valor inválido para %s: %v", filter.name, args[filter.name])), nil
+		}
+		if value > 0 || strings.HasPrefix(filter.name, "max_") {
+			from += " AND " + fmt.Sprintf(filter.condition, arg(value))
+		}
+	}
+
+	for _, item := range request.GetStringSlice("safety", nil) {
+		if !isSafetyColumn(item) {
+			return mcp.NewToolResultError(fmt.Sprintf("item de segurança desconhecido: %s (use: %s)", item, strings.Join(safetyColumns, ", "))), nil
+		}
+		from += fmt.Sprintf(" AND COALESCE(sv.%s, false)", item)
+	}
+
+	standard := request.GetStringSlice("standard_features", nil)
+	available := request.GetStringSlice("features", nil)
+	for _, feature := range standard {
+		from += fmt.Sprintf(` AND EXISTS (SELECT 1 FROM veiculo_caracteristicas c
+			WHERE c.id_veiculos = v.id_veiculos AND c.tipo = 'Serie' AND c.item ILIKE %s)`, arg(containsPattern(feature)))
+	}
+	for _, feature := range available {
+		from += fmt.Sprintf(` AND EXISTS (SELECT 1 FROM veiculo_caracteristicas c
+			WHERE c.id_veiculos = v.id_veiculos AND c.item ILIKE %s)`, arg(containsPattern(feature)))
+	}
+
+	sort := request.GetString("sort", "price_asc")
+	orderBy, ok := searchSorts[sort]
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("ordenação desconhecida: %s", sort)), nil
+	}
+
+	page := request.GetInt("page", 1)
+	pageSize := request.GetInt("page_size", defaultSearchPageSize)
+	if page < 1 || pageSize < 1 || pageSize > maxSearchPageSize {
+		return mcp.NewToolResultError(fmt.Sprintf("page deve ser a partir de 1 e page_size entre 1 e %d", maxSearchPageSize)), nil
+	}
+
+	result := VehicleSearch{Page: page, PageSize: pageSize, Vehicles: []SearchedVehicle{}}
+	if err := s.DB.QueryRowContext(ctx, "SELECT COUNT(*)"+from, queryArgs...).Scan(&result.Total); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("erro ao contar veículos: %v", err)), nil
+	}
+
+	query := `
+		SELECT
+			v.id_veiculos,
+			m.marca,
+			mo.modelo,
+			COALESCE(v.versao, ''),
+			v.preco_venda,
+			v.tipo_veiculo,
+			v.status_veiculo,
+			COALESCE(v.consumo_urbano, 0),
+			COALESCE(v.consumo_rodoviario, 0),
+			COALESCE(v.potencia_cv, 0),
+			COALESCE(v.ipva_anual, 0),
+			v.ano_modelo,
+			COALESCE(v.cor, ''),
+			COALESCE(v.tipo_combustivel, ''),
+			mo.categoria,
+			COALESCE(v.quilometragem, 0),
+			sv.nota_latin_ncap
+	` + from + fmt.Sprintf(" ORDER BY %s, v.id_veiculos LIMIT %s OFFSET %s", orderBy, arg(pageSize), arg((page-1)*pageSize))
+
+	rows, err := s.DB.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("erro ao buscar veículos: %v", err)), nil
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v SearchedVehicle
+		var ncap sql.NullFloat64
+		err := rows.Scan(&v.ID, &v.Brand, &v.Model, &v.Version, &v.Price, &v.VehicleType, &v.Status,
+			&v.UrbanConsumption, &v.HighwayConsumption, &v.Horsepower, &v.AnnualIPVA, &v.ModelYear, &v.Color, &v.FuelType,
+			&v.Category, &v.Km, &ncap)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("erro ao escanear linha: %v", err)), nil
+		}
+		v.NCAP = nullFloat(ncap)
+		result.Vehicles = append(result.Vehicles, v)
+	}
+	if err := rows.Err(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("erro ao buscar veículos: %v", err)), nil
+	}
+	result.Pages = (result.Total + pageSize - 1) / pageSize
+
+	if err := s.enrichSearchResults(ctx, result.Vehicles, append(standard, available...)); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+// enrichSearchResults aplica campanhas, alertas de recall e incentivos fiscais
+// e lista os itens pedidos que cada veículo tem, separando série e opcionais.
+func (s *Server) enrichSearchResults(ctx context.Context, vehicles []SearchedVehicle, features []string) error {
+	if len(vehicles) == 0 {
+		return nil
+	}
+
+	plain := make([]Vehicle, len(vehicles))
+	for i := range vehicles {
+		plain[i] = vehicles[i].Vehicle
+	}
+	if err := s.applyCampaignPrices(ctx, plain); err != nil {
+		return err
+	}
+	if err := s.applyRecallAlerts(ctx, plain); err != nil {
+		return err
+	}
+	if err := s.applyIncentives(ctx, plain); err != nil {
+		return err
+	}
+	byID := make(map[int]*SearchedVehicle, len(vehicles))
+	for i := range vehicles {
+		vehicles[i].Vehicle = plain[i]
+		byID[vehicles[i].ID] = &vehicles[i]
+	}
+
+	if len(features) == 0 {
+		return nil
+	}
+
+	query := `
+		SELECT id_veiculos, item, tipo, COALESCE(preco_adicional, 0)
+		FROM veiculo_caracteristicas
+		WHERE id_veiculos = ANY($1) AND item ILIKE ANY($2)
+		ORDER BY id_veiculos, tipo DESC, item
+	`
+
+	ids := make([]int64, len(vehicles))
+	for i, v := range vehicles {
+		ids[i] = int64(v.ID)
+	}
+	patterns := make([]string, len(features))
+	for i, feature := range features {
+		patterns[i] = containsPattern(feature)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, query, pq.Array(ids), pq.Array(patterns))
+	if err != nil {
+		return fmt.Errorf("erro ao buscar itens dos veículos: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var item, kind string
+		var price float64
+		if err := rows.Scan(&id, &item, &kind, &price); err != nil {
+			return fmt.Errorf("erro ao ler item do veículo: %w", err)
+		}
+		v := byID[id]
+		if kind == "Serie" {
+			v.StandardItems = append(v.StandardItems, item)
+		} else {
+			v.OptionalItems = append(v.OptionalItems, OptionalItem{Item: item, Price: price})
+		}
+	}
+	return rows.Err()
+}
@@ -4,11 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"mcp-gemini-go/internal/finance"
+
+	"github.com/lib/pq"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
@@ -25,6 +32,16 @@ type Server struct {
 	DB     *sql.DB
 	config *DBConfig
 	mcp    *server.MCPServer
+	tools  []server.ServerTool
+	policy *Policy
+
+	// Limites aplicados às consultas livres de execute_sql.
+	queryTimeout time.Duration
+	maxRows      int
+
+	// Regras de crédito de check_affordability.
+	maxIncomeRatio float64
+	creditTiers    []creditTier
 }
 
 func NewServer() *Server {
@@ -35,7 +52,13 @@ func NewServer() *Server {
 		User:     getEnv("DB_USER", "user"),
 		Password: getEnv("DB_PASSWORD", "password"),
 	}
-	return &Server{config: config}
+	return &Server{
+		config:       config,
+		queryTimeout: getEnvDuration("SQL_STATEMENT_TIMEOUT", 5*time.Second),
+		maxRows:      getEnvInt("SQL_MAX_ROWS", 100),
+
+		maxIncomeRatio: getEnvFloat("AFFORDABILITY_MAX_INCOME_PERCENT", 30) / 100,
+	}
 }
 
 func (s *Server) Connect() error {
@@ -57,37 +80,142 @@ func (s *Server) Connect() error {
 }
 
 func (s *Server) Initialize() error {
+	policy, err := LoadPolicy(os.Getenv("SQL_POLICY_FILE"))
+	if err != nil {
+		return err
+	}
+	s.policy = policy
+
+	tiers, err := parseCreditTiers(getEnv("CREDIT_SCORE_TIERS", defaultCreditTiers))
+	if err != nil {
+		return fmt.Errorf("CREDIT_SCORE_TIERS inválido: %w", err)
+	}
+	s.creditTiers = tiers
+
 	s.mcp = server.NewMCPServer(
 		"SQL Server",
 		"1.0.0",
+		server.WithRecovery(),
 	)
 
-	s.mcp.AddTool(mcp.NewTool("get_schema",
-		mcp.WithDescription("Retorna schema do banco com informações dos veículos e financiamentos"),
+	s.addTool(mcp.NewTool("get_schema",
+		mcp.WithDescription("Retorna schema do banco com informações dos veículos e financiamentos, conforme as permissões do cliente"),
 	), s.GetSchema)
 
-	s.mcp.AddTool(mcp.NewTool("execute_sql",
-		mcp.WithDescription("Executa uma consulta SQL no banco de dados da concessionária"),
+	s.addTool(mcp.NewTool("execute_sql",
+		mcp.WithDescription("Executa uma consulta SQL somente leitura (uma única instrução SELECT) no banco de dados da concessionária; apenas tabelas e colunas listadas em get_schema estão disponíveis"),
 		mcp.WithString("query",
 			mcp.Required(),
-			mcp.Description("Consulta SQL para executar"),
+			mcp.Description("Consulta SELECT para executar; o resultado é limitado em linhas e tempo"),
 		),
 	), s.ExecuteSQL)
 
-	s.mcp.AddTool(mcp.NewTool("get_vehicles_available",
-		mcp.WithDescription("Busca veículos disponíveis com filtros opcionais"),
+	s.addTool(mcp.NewTool("get_vehicles_available",
+		mcp.WithDescription("Busca veículos disponíveis com filtros opcionais; inclui o preço promocional quando há campanha vigente para o modelo"),
 		mcp.WithNumber("max_price",
 			mcp.Description("Preço máximo"),
 		),
+		mcp.WithNumber("min_price",
+			mcp.Description("Preço mínimo"),
+		),
 		mcp.WithString("brand",
 			mcp.Description("Marca do veículo"),
 		),
+		mcp.WithString("model",
+			mcp.Description("Modelo do veículo"),
+		),
+		mcp.WithNumber("near_price",
+			mcp.Description("Ordena pelos veículos com preço mais próximo deste valor"),
+		),
 		mcp.WithString("type",
 			mcp.Description("Tipo do veículo (Novo, Usado, Seminovo)"),
+			mcp.Enum("Novo", "Usado", "Seminovo"),
+		),
+		mcp.WithString("sort",
+			mcp.Description("Ordenação por preço: 'cheap' (mais baratos primeiro) ou 'expensive' (mais caros primeiro)"),
+			mcp.Enum("cheap", "expensive"),
 		),
 	), s.GetVehiclesAvailable)
 
-	s.mcp.AddTool(mcp.NewTool("get_best_financing",
+	s.addTool(mcp.NewTool("search_vehicles",
+		mcp.WithDescription("Busca veículos disponíveis por itens de série e opcionais, itens de segurança, nota Latin NCAP, combustível, categoria, potência, consumo, ano e quilometragem, com ordenação, paginação e o total de resultados"),
+		mcp.WithArray("standard_features",
+			mcp.Description("Itens que devem ser de série (trecho do nome, ex.: 'Câmera de Ré', 'Piloto Automático Adaptativo')"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("features",
+			mcp.Description("Itens que devem estar disponíveis, de série ou como opcional (trecho do nome)"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("safety",
+			mcp.Description("Itens de segurança obrigatórios"),
+			mcp.Items(map[string]any{"type": "string", "enum": safetyColumns}),
+		),
+		mcp.WithNumber("min_ncap",
+			mcp.Description("Nota mínima no Latin NCAP (0 a 5 estrelas)"),
+		),
+		mcp.WithString("fuel_type",
+			mcp.Description("Tipo de combustível"),
+			mcp.Enum("Gasolina", "Etanol", "Flex", "Diesel", "Hibrido", "Eletrico", "GNV"),
+		),
+		mcp.WithString("category",
+			mcp.Description("Categoria do modelo"),
+			mcp.Enum("Hatch", "Sedan", "SUV", "Crossover", "Minivan", "Picape", "Utilitarios", "Esportivos"),
+		),
+		mcp.WithString("brand",
+			mcp.Description("Marca do veículo"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Modelo do veículo"),
+		),
+		mcp.WithString("type",
+			mcp.Description("Tipo do veículo (Novo, Usado, Seminovo)"),
+			mcp.Enum("Novo", "Usado", "Seminovo"),
+		),
+		mcp.WithNumber("min_price",
+			mcp.Description("Preço mínimo"),
+		),
+		mcp.WithNumber("max_price",
+			mcp.Description("Preço máximo"),
+		),
+		mcp.WithNumber("min_power",
+			mcp.Description("Potência mínima em cv"),
+		),
+		mcp.WithNumber("max_power",
+			mcp.Description("Potência máxima em cv"),
+		),
+		mcp.WithNumber("min_urban_consumption",
+			mcp.Description("Consumo urbano mínimo em km/l"),
+		),
+		mcp.WithNumber("min_highway_consumption",
+			mcp.Description("Consumo rodoviário mínimo em km/l"),
+		),
+		mcp.WithNumber("min_year",
+			mcp.Description("Ano-modelo mínimo"),
+		),
+		mcp.WithNumber("max_year",
+			mcp.Description("Ano-modelo máximo"),
+		),
+		mcp.WithNumber("max_km",
+			mcp.Description("Quilometragem máxima"),
+		),
+		mcp.WithString("sort",
+			mcp.Description("Ordenação (padrão: price_asc)"),
+			mcp.Enum("price_asc", "price_desc", "power_desc", "consumption_desc", "year_desc", "km_asc", "ncap_desc"),
+		),
+		mcp.WithNumber("page",
+			mcp.Description("Página, a partir de 1 (padrão: 1)"),
+		),
+		mcp.WithNumber("page_size",
+			mcp.Description(fmt.Sprintf("Veículos por página, até %d (padrão: %d)", maxSearchPageSize, defaultSearchPageSize)),
+		),
+	), s.SearchVehicles)
+
+	s.addTool(mcp.NewTool("get_vehicle_price_stats",
+		mcp.WithDescription("Retorna quantidade e preços mínimo, médio e máximo dos veículos disponíveis"),
+	), s.GetVehiclePriceStats)
+
+	s.addTool(mcp.NewTool("get_best_financing",
 		mcp.WithDescription("Busca melhores opções de financiamento"),
 		mcp.WithNumber("vehicle_price",
 			mcp.Description("Preço do veículo"),
@@ -95,10 +223,14 @@ func (s *Server) Initialize() error {
 		mcp.WithNumber("max_installments",
 			mcp.Description("Número máximo de parcelas"),
 		),
+		mcp.WithString("type",
+			mcp.Description("Tipo de financiamento"),
+			mcp.Enum("CDC", "Leasing", "Consorcio", "A Vista"),
+		),
 	), s.GetBestFinancing)
 
-	s.mcp.AddTool(mcp.NewTool("calculate_financing",
-		mcp.WithDescription("Calcula financiamento com simulação detalhada"),
+	s.addTool(mcp.NewTool("calculate_financing",
+		mcp.WithDescription("Calcula financiamento CDC pela Tabela Price ou SAC usando a taxa mensal do banco"),
 		mcp.WithNumber("vehicle_price",
 			mcp.Required(),
 			mcp.Description("Preço do veículo"),
@@ -106,24 +238,401 @@ func (s *Server) Initialize() error {
 		mcp.WithNumber("down_payment",
 			mcp.Description("Valor da entrada"),
 		),
+		mcp.WithNumber("trade_in_value",
+			mcp.Description("Valor líquido do usado na troca (valor_liquido de appraise_trade_in), somado à entrada"),
+		),
 		mcp.WithNumber("installments",
 			mcp.Required(),
-			mcp.Description("Número de parcelas"),
+			mcp.Description(fmt.Sprintf("Número de parcelas (entre %d e %d)", finance.MinTerm, finance.MaxTerm)),
 		),
 		mcp.WithString("bank",
-			mcp.Description("Banco para financiamento"),
+			mcp.Description("Banco para financiamento (padrão: banco com a menor taxa)"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Modelo do veículo, para aplicar a taxa especial de campanhas vigentes"),
+		),
+		mcp.WithString("system",
+			mcp.Description("Sistema de amortização: 'price' (parcelas iguais, padrão) ou 'sac' (parcelas decrescentes)"),
+			mcp.Enum(string(finance.Price), string(finance.SAC)),
+		),
+		mcp.WithBoolean("include_schedule",
+			mcp.Description("Inclui a tabela de amortização mês a mês (juros, amortização e saldo)"),
 		),
 	), s.CalculateFinancing)
 
+	s.addTool(mcp.NewTool("solve_financing",
+		mcp.WithDescription("Resolve o financiamento pela Tabela Price: informe três entre preço, entrada, parcela e prazo e a ferramenta calcula o que falta (para o prazo, o menor que cabe na parcela)"),
+		mcp.WithNumber("vehicle_price",
+			mcp.Description("Preço do veículo"),
+		),
+		mcp.WithNumber("down_payment",
+			mcp.Description("Valor da entrada em dinheiro (0 para sem entrada); quando calculada, inclui a troca"),
+		),
+		mcp.WithNumber("trade_in_value",
+			mcp.Description("Valor líquido do usado na troca (valor_liquido de appraise_trade_in), somado à entrada"),
+		),
+		mcp.WithNumber("installment",
+			mcp.Description("Valor da parcela mensal desejada"),
+		),
+		mcp.WithNumber("installments",
+			mcp.Description(fmt.Sprintf("Número de parcelas (entre %d e %d)", finance.MinTerm, finance.MaxTerm)),
+		),
+		mcp.WithString("bank",
+			mcp.Description("Banco para financiamento (padrão: banco com a menor taxa)"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Modelo do veículo, para aplicar a taxa especial de campanhas vigentes"),
+		),
+	), s.SolveFinancing)
+
+	s.addTool(mcp.NewTool("check_affordability",
+		mcp.WithDescription("Verifica quais veículos disponíveis e financiamentos CDC cabem na renda do cliente, com o preço promocional das campanhas vigentes, aplicando o teto de comprometimento da renda e a faixa de score, com o motivo de cada opção recusada"),
+		mcp.WithNumber("customer_id",
+			mcp.Description("ID do cliente cadastrado (usa renda e score do cadastro)"),
+		),
+		mcp.WithNumber("monthly_income",
+			mcp.Description("Renda mensal, quando não há cliente cadastrado"),
+		),
+		mcp.WithNumber("credit_score",
+			mcp.Description("Score de crédito (0 a 1000), quando não há cliente cadastrado"),
+		),
+		mcp.WithNumber("down_payment",
+			mcp.Description("Valor da entrada"),
+		),
+		mcp.WithNumber("trade_in_value",
+			mcp.Description("Valor líquido do usado na troca (valor_liquido de appraise_trade_in), somado à entrada"),
+		),
+	), s.CheckAffordability)
+
+	s.addTool(mcp.NewTool("calculate_cet",
+		mcp.WithDescription("Calcula o Custo Efetivo Total (CET) de um financiamento, somando juros, IOF, TAC, registro do gravame e seguro prestamista"),
+		mcp.WithNumber("vehicle_price",
+			mcp.Required(),
+			mcp.Description("Preço do veículo"),
+		),
+		mcp.WithNumber("down_payment",
+			mcp.Description("Valor da entrada"),
+		),
+		mcp.WithNumber("trade_in_value",
+			mcp.Description("Valor líquido do usado na troca (valor_liquido de appraise_trade_in), somado à entrada"),
+		),
+		mcp.WithNumber("installments",
+			mcp.Required(),
+			mcp.Description(fmt.Sprintf("Número de parcelas (entre %d e %d)", finance.MinTerm, finance.MaxTerm)),
+		),
+		mcp.WithString("bank",
+			mcp.Description("Banco para financiamento (padrão: banco com a menor taxa)"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Modelo do veículo, para aplicar a taxa especial de campanhas vigentes"),
+		),
+		mcp.WithString("system",
+			mcp.Description("Sistema de amortização: 'price' (padrão) ou 'sac'"),
+			mcp.Enum(string(finance.Price), string(finance.SAC)),
+		),
+		mcp.WithNumber("tac",
+			mcp.Description("Tarifa de abertura de crédito/cadastro em reais (padrão: 0)"),
+		),
+		mcp.WithNumber("registration_fee",
+			mcp.Description("Taxa de registro do gravame em reais (padrão: 0)"),
+		),
+		mcp.WithNumber("insurance_rate",
+			mcp.Description("Seguro prestamista em % do valor financiado (padrão: sem seguro)"),
+		),
+		mcp.WithBoolean("finance_fees",
+			mcp.Description("Inclui tarifas, seguro e IOF no valor financiado (padrão: true); se false, são pagos na contratação"),
+		),
+	), s.CalculateCET)
+
+	s.addTool(mcp.NewTool("compare_payment_options",
+		mcp.WithDescription("Compara CDC, leasing, consórcio e compra à vista para o mesmo veículo com um resumo de custos no mesmo formato (total pago, custo nominal, custo a valor presente, custo efetivo mensal e mês de recebimento) e indica a opção mais barata a valor presente"),
+		mcp.WithNumber("vehicle_price",
+			mcp.Required(),
+			mcp.Description("Preço do veículo"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Modelo do veículo, para aplicar campanhas vigentes (desconto à vista e taxa especial no CDC)"),
+		),
+		mcp.WithNumber("down_payment",
+			mcp.Description("Valor da entrada; no consórcio, paga na contemplação para completar a carta de crédito"),
+		),
+		mcp.WithNumber("trade_in_value",
+			mcp.Description("Valor líquido do usado na troca (valor_liquido de appraise_trade_in), somado à entrada"),
+		),
+		mcp.WithNumber("installments",
+			mcp.Description(fmt.Sprintf("Número de parcelas do CDC e do leasing, entre %d e %d (padrão: prazo da condição do banco)", finance.MinTerm, finance.MaxTerm)),
+		),
+		mcp.WithString("bank",
+			mcp.Description("Instituição (padrão: a de menor taxa em cada modalidade)"),
+		),
+		mcp.WithNumber("vrg_percent",
+			mcp.Description("Valor residual garantido do leasing em % do preço (padrão: 0)"),
+		),
+		mcp.WithString("vrg_mode",
+			mcp.Description("Pagamento do VRG: 'final' (no fim do contrato, padrão), 'antecipado' (junto com a entrada) ou 'diluido' (nas parcelas)"),
+			mcp.Enum(string(finance.VRGFinal), string(finance.VRGUpfront), string(finance.VRGDiluted)),
+		),
+		mcp.WithNumber("admin_fee",
+			mcp.Description("Taxa de administração total do consórcio em % da carta de crédito; sem ela o consórcio fica fora da comparação"),
+		),
+		mcp.WithNumber("reserve_fund",
+			mcp.Description("Fundo de reserva do consórcio em % da carta de crédito (padrão: 0)"),
+		),
+		mcp.WithNumber("contemplation_month",
+			mcp.Description("Mês de contemplação do consórcio no cenário simulado (padrão: metade do prazo)"),
+		),
+		mcp.WithNumber("cash_discount",
+			mcp.Description("Desconto negociado na compra à vista em %, aplicado após as campanhas"),
+		),
+		mcp.WithNumber("discount_rate",
+			mcp.Description(fmt.Sprintf("Custo de oportunidade do cliente em %% ao mês, para trazer os fluxos a valor presente (padrão: %.1f)", defaultDiscountRate)),
+		),
+	), s.ComparePaymentOptions)
+
+	s.addTool(mcp.NewTool("get_active_campaigns",
+		mcp.WithDescription("Lista as campanhas e promoções vigentes, com datas, descontos, taxa especial de juros e condições"),
+		mcp.WithString("model",
+			mcp.Description("Modelo do veículo (padrão: todos os modelos)"),
+		),
+	), s.GetActiveCampaigns)
+
+	s.addTool(mcp.NewTool("calculate_tco",
+		mcp.WithDescription("Calcula o custo total de posse (TCO) ano a ano: IPVA, licenciamento, seguro, manutenção, combustível e depreciação, com o custo mensal real e o valor de revenda projetado"),
+		mcp.WithNumber("vehicle_id",
+			mcp.Description("ID do veículo (id_veiculo retornado por get_vehicles_available)"),
+		),
+		mcp.WithString("brand",
+			mcp.Description("Marca do veículo, quando não há vehicle_id"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Modelo do veículo, quando não há vehicle_id"),
+		),
+		mcp.WithNumber("years",
+			mcp.Description("Horizonte em anos, de 1 a 10 (padrão: 5)"),
+		),
+		mcp.WithNumber("annual_km",
+			mcp.Description("Quilometragem rodada por ano (padrão: 15000)"),
+		),
+		mcp.WithNumber("fuel_price",
+			mcp.Required(),
+			mcp.Description("Preço do litro de combustível em reais"),
+		),
+		mcp.WithNumber("urban_percent",
+			mcp.Description("Percentual da quilometragem rodada na cidade (padrão: 60)"),
+		),
+		mcp.WithNumber("driver_age",
+			mcp.Description("Idade do condutor, para a cotação de seguro"),
+		),
+		mcp.WithString("driver_gender",
+			mcp.Description("Gênero do condutor, para a cotação de seguro"),
+			mcp.Enum("Masculino", "Feminino"),
+		),
+		mcp.WithString("city",
+			mcp.Description("Cidade de circulação, para a cotação de seguro e os incentivos fiscais no IPVA"),
+		),
+		mcp.WithNumber("license_years",
+			mcp.Description("Anos de habilitação do condutor, para a cotação de seguro"),
+		),
+	), s.CalculateTCO)
+
+	s.addTool(mcp.NewTool("estimate_insurance",
+		mcp.WithDescription("Estima o seguro anual (mínimo, médio e máximo) e a franquia para o perfil do condutor, usando o segmento mais próximo ou a média nacional e o índice de roubo e furto da cidade"),
+		mcp.WithNumber("vehicle_id",
+			mcp.Description("ID do veículo (id_veiculo retornado por get_vehicles_available)"),
+		),
+		mcp.WithString("brand",
+			mcp.Description("Marca do veículo, quando não há vehicle_id"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Modelo do veículo, quando não há vehicle_id"),
+		),
+		mcp.WithString("version",
+			mcp.Description("Versão do veículo (trecho do nome), quando não há vehicle_id"),
+		),
+		mcp.WithNumber("driver_age",
+			mcp.Description("Idade do condutor"),
+		),
+		mcp.WithString("driver_gender",
+			mcp.Description("Gênero do condutor"),
+			mcp.Enum("Masculino", "Feminino"),
+		),
+		mcp.WithString("city",
+			mcp.Description("Cidade de circulação do veículo"),
+		),
+		mcp.WithNumber("license_years",
+			mcp.Description("Anos de habilitação (CNH) do condutor"),
+		),
+		mcp.WithString("coverage",
+			mcp.Description("Tipo de cobertura desejada"),
+			mcp.Enum("Básica", "Intermediária", "Completa"),
+		),
+	), s.EstimateInsurance)
+
+	s.addTool(mcp.NewTool("appraise_trade_in",
+		mcp.WithDescription("Avalia o usado dado na troca pelo histórico de valorização e por avaliações comparáveis, ajustando ano, quilometragem e estado, e desconta os débitos; o valor_liquido pode ser usado como trade_in_value nas simulações"),
+		mcp.WithString("brand",
+			mcp.Description("Marca do usado"),
+		),
+		mcp.WithString("model",
+			mcp.Required(),
+			mcp.Description("Modelo do usado"),
+		),
+		mcp.WithNumber("year",
+			mcp.Required(),
+			mcp.Description("Ano-modelo do usado"),
+		),
+		mcp.WithNumber("km",
+			mcp.Required(),
+			mcp.Description("Quilometragem do usado"),
+		),
+		mcp.WithString("condition",
+			mcp.Description("Estado geral do usado (padrão: Bom)"),
+			mcp.Enum("Excelente", "Muito Bom", "Bom", "Regular", "Ruim"),
+		),
+		mcp.WithNumber("debts",
+			mcp.Description("Débitos pendentes do usado em reais (IPVA, multas, saldo de financiamento)"),
+		),
+	), s.AppraiseTradeIn)
+
+	s.addTool(mcp.NewTool("check_recalls",
+		mcp.WithDescription("Lista os recalls e problemas conhecidos abertos e resolvidos de um veículo ou de um modelo e ano-modelo, com gravidade, solução e alerta para recalls Critica ou Alta não resolvidos"),
+		mcp.WithNumber("vehicle_id",
+			mcp.Description("ID do veículo (id_veiculo retornado por get_vehicles_available)"),
+		),
+		mcp.WithString("brand",
+			mcp.Description("Marca, quando não há vehicle_id"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Modelo, quando não há vehicle_id"),
+		),
+		mcp.WithNumber("year",
+			mcp.Description("Ano-modelo, quando não há vehicle_id (padrão: todos os anos)"),
+		),
+	), s.CheckRecalls)
+
+	s.addTool(mcp.NewTool("get_tax_incentives",
+		mcp.WithDescription("Lista os incentivos fiscais vigentes (redução ou isenção de IPVA, isenção de rodízio) para o modelo e o combustível do veículo na cidade do cliente, com o IPVA ajustado"),
+		mcp.WithNumber("vehicle_id",
+			mcp.Description("ID do veículo (id_veiculo retornado por get_vehicles_available)"),
+		),
+		mcp.WithString("brand",
+			mcp.Description("Marca do veículo, quando não há vehicle_id"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Modelo do veículo, quando não há vehicle_id"),
+		),
+		mcp.WithString("version",
+			mcp.Description("Versão do veículo (trecho do nome), quando não há vehicle_id"),
+		),
+		mcp.WithString("city",
+			mcp.Description("Cidade do cliente (sem cidade, só entram incentivos sem restrição de cidade)"),
+		),
+	), s.GetTaxIncentives)
+
+	s.addTool(mcp.NewTool("forecast_resale_value",
+		mcp.WithDescription("Projeta o valor de revenda em 1, 3 e 5 anos pela curva de depreciação ajustada ao histórico do modelo, com faixa de confiança, nível de depreciação e liquidez de mercado (facilidade de venda e tempo médio de venda)"),
+		mcp.WithNumber("vehicle_id",
+			mcp.Description("ID do veículo (id_veiculo retornado por get_vehicles_available)"),
+		),
+		mcp.WithString("brand",
+			mcp.Description("Marca do veículo, quando não há vehicle_id"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Modelo do veículo, quando não há vehicle_id"),
+		),
+		mcp.WithString("version",
+			mcp.Description("Versão do veículo (trecho do nome), quando não há vehicle_id"),
+		),
+		mcp.WithNumber("price",
+			mcp.Description("Valor de compra usado como base (padrão: preço de venda do veículo)"),
+		),
+	), s.ForecastResaleValue)
+
+	s.addTool(mcp.NewTool("get_maintenance_plan",
+		mcp.WithDescription("Monta o plano de manutenção datado (serviço, mês, quilometragem, peças e mão de obra) para os primeiros anos ou quilômetros de uso, com totais por ano, alertas de peças com baixa disponibilidade e, para usados, sem repetir os serviços já feitos"),
+		mcp.WithNumber("vehicle_id",
+			mcp.Description("ID do veículo (id_veiculo retornado por get_vehicles_available)"),
+		),
+		mcp.WithString("brand",
+			mcp.Description("Marca do veículo, quando não há vehicle_id"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Modelo do veículo, quando não há vehicle_id"),
+		),
+		mcp.WithString("version",
+			mcp.Description("Versão do veículo (trecho do nome), quando não há vehicle_id"),
+		),
+		mcp.WithNumber("years",
+			mcp.Description("Horizonte em anos, de 1 a 10 (padrão: 3)"),
+		),
+		mcp.WithNumber("km",
+			mcp.Description("Quilômetros a rodar no plano, até 10 anos de uso na annual_km; com years, vale o que vier primeiro"),
+		),
+		mcp.WithNumber("annual_km",
+			mcp.Description("Quilometragem rodada por ano (padrão: 15000)"),
+		),
+	), s.GetMaintenancePlan)
+
+	s.addTool(mcp.NewTool("compare_vehicles",
+		mcp.WithDescription(fmt.Sprintf("Compara de %d a %d veículos lado a lado (preço, desempenho, consumo, equipamentos, segurança, impacto ambiental, recalls e depreciação), marcando o vencedor de cada dimensão", minComparedVehicles, maxComparedVehicles)),
+		mcp.WithArray("vehicle_ids",
+			mcp.Description("IDs dos veículos (id_veiculo retornado por get_vehicles_available)"),
+			mcp.Items(map[string]any{"type": "number"}),
+		),
+		mcp.WithArray("vehicles",
+			mcp.Description("Veículos por marca, modelo e versão, quando não há IDs"),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"brand":   map[string]any{"type": "string", "description": "Marca"},
+					"model":   map[string]any{"type": "string", "description": "Modelo"},
+					"version": map[string]any{"type": "string", "description": "Versão (trecho do nome)"},
+				},
+				"required": []string{"model"},
+			}),
+		),
+		mcp.WithString("city",
+			mcp.Description("Cidade do cliente, para aplicar os incentivos fiscais ao IPVA"),
+		),
+	), s.CompareVehicles)
+
 	log.Println("🚀 Servidor MCP SQL inicializado")
 	return nil
 }
 
+// addTool registra a ferramenta no servidor MCP e guarda o registro em
+// s.tools, que os testes usam para conferir os argumentos lidos pelos handlers.
+func (s *Server) addTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	s.tools = append(s.tools, server.ServerTool{Tool: tool, Handler: handler})
+	s.mcp.AddTool(tool, handler)
+}
+
 func (s *Server) GetMCPServer() *server.MCPServer {
 	return s.mcp
 }
 
 func (s *Server) GetSchema(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	columns, err := loadSchemaColumns(ctx, s.DB)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("erro ao consultar schema: %v", err)), nil
+	}
+
+	role, rolePolicy := s.policy.RoleFor(clientName(ctx))
+	result := map[string]interface{}{
+		"schema":      rolePolicy.VisibleSchema(columns),
+		"perfil":      role,
+		"description": "Banco de dados da concessionária com veículos, financiamentos e vendas",
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func loadSchemaColumns(ctx context.Context, db queryer) (map[string][]schemaColumn, error) {
 	query := `
 		SELECT table_name, column_name, data_type
 		FROM information_schema.columns
@@ -131,31 +640,22 @@ func (s *Server) GetSchema(ctx context.Context, request mcp.CallToolRequest) (*m
 		ORDER BY table_name, ordinal_position
 	`
 
-	rows, err := s.DB.QueryContext(ctx, query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao consultar schema: %w", err)
+		return nil, err
 	}
 	defer rows.Close()
 
-	schema := make(map[string][]string)
+	columns := make(map[string][]schemaColumn)
 	for rows.Next() {
-		var tableName, columnName, dataType string
-		if err := rows.Scan(&tableName, &columnName, &dataType); err != nil {
+		var tableName string
+		var column schemaColumn
+		if err := rows.Scan(&tableName, &column.Name, &column.Type); err != nil {
 			return nil, err
 		}
-
-		if _, exists := schema[tableName]; !exists {
-			schema[tableName] = make([]string, 0)
-		}
-		schema[tableName] = append(schema[tableName], fmt.Sprintf("%s (%s)", columnName, dataType))
+		columns[tableName] = append(columns[tableName], column)
 	}
-
-	result := map[string]interface{}{
-		"schema":      schema,
-		"description": "Banco de dados da concessionária com veículos, financiamentos e vendas",
-	}
-
-	return mcp.NewToolResultText(fmt.Sprintf("%+v", result)), nil
+	return columns, rows.Err()
 }
 
 func (s *Server) ExecuteSQL(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -164,21 +664,57 @@ func (s *Server) ExecuteSQL(ctx context.Context, request mcp.CallToolRequest) (*
 		return mcp.NewToolResultError(fmt.Sprintf("parâmetro 'query' é obrigatório: %v", err)), nil
 	}
 
-	rows, err := s.DB.QueryContext(ctx, query)
+	log.Printf("🔎 execute_sql solicitado por %s", clientName(ctx))
+
+	if err := validateReadOnlyQuery(query); err != nil {
+		log.Printf("🚫 execute_sql rejeitado para %s: %v", clientName(ctx), err)
+		return err.(queryRejection).toolResult(), nil
+	}
+
+	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("erro ao iniciar transação: %v", err)), nil
+	}
+	defer tx.Rollback()
+
+	timeout := fmt.Sprintf("SET LOCAL statement_timeout = %d", s.queryTimeout.Milliseconds())
+	if _, err := tx.ExecContext(ctx, timeout); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("erro ao configurar timeout: %v", err)), nil
+	}
+
+	columns, err := loadSchemaColumns(ctx, tx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("erro ao consultar schema: %v", err)), nil
+	}
+
+	role, rolePolicy := s.policy.RoleFor(clientName(ctx))
+	query, err = rolePolicy.Rewrite(query, columns)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("erro ao executar query: %v", err)), nil
+		log.Printf("🚫 execute_sql negado para %s (perfil %s): %v", clientName(ctx), role, err)
+		return err.(queryRejection).toolResult(), nil
+	}
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return s.queryError(err), nil
 	}
 	defer rows.Close()
 
-	columns, err := rows.Columns()
+	resultColumns, err := rows.Columns()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("erro ao obter colunas: %v", err)), nil
 	}
 
-	var results []map[string]interface{}
+	results := []map[string]interface{}{}
+	truncated := false
 	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
+		if len(results) == s.maxRows {
+			truncated = true
+			break
+		}
+
+		values := make([]interface{}, len(resultColumns))
+		valuePtrs := make([]interface{}, len(resultColumns))
 		for i := range values {
 			valuePtrs[i] = &values[i]
 		}
@@ -188,7 +724,7 @@ func (s *Server) ExecuteSQL(ctx context.Context, request mcp.CallToolRequest) (*
 		}
 
 		row := make(map[string]interface{})
-		for i, col := range columns {
+		for i, col := range resultColumns {
 			val := values[i]
 			if b, ok := val.([]byte); ok {
 				row[col] = string(b)
@@ -198,14 +734,45 @@ func (s *Server) ExecuteSQL(ctx context.Context, request mcp.CallToolRequest) (*
 		}
 		results = append(results, row)
 	}
+	if err := rows.Err(); err != nil {
+		return s.queryError(err), nil
+	}
 
-	resultJSON, _ := json.Marshal(results)
+	resultJSON, _ := json.Marshal(map[string]interface{}{
+		"linhas":     results,
+		"truncado":   truncated,
+		"max_linhas": s.maxRows,
+	})
 	return mcp.NewToolResultText(string(resultJSON)), nil
 }
 
+// queryError traduz cancelamento por timeout e tentativas de escrita em
+// rejeições estruturadas; os demais erros do banco seguem como texto.
+func (s *Server) queryError(err error) *mcp.CallToolResult {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "57014":
+			return queryRejection{
+				Code:   rejectTimeout,
+				Reason: fmt.Sprintf("a consulta excedeu o limite de %s", s.queryTimeout),
+				Hint:   "adicione filtros, use LIMIT ou agregue os dados",
+			}.toolResult()
+		case "25006":
+			return queryRejection{
+				Code:   rejectReadOnly,
+				Reason: "a consulta tentou alterar dados numa transação somente leitura",
+				Hint:   "use apenas SELECT",
+			}.toolResult()
+		}
+	}
+	return mcp.NewToolResultError(fmt.Sprintf("erro ao executar query: %v", err))
+}
+
 func (s *Server) GetVehiclesAvailable(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	query := `
 		SELECT 
+			v.id_veiculos,
 			m.marca,
 			mo.modelo,
 			v.versao,
@@ -249,6 +816,13 @@ func (s *Server) GetVehiclesAvailable(ctx context.Context, request mcp.CallToolR
 		argIndex++
 	}
 
+	model := request.GetString("model", "")
+	if model != "" {
+		query += fmt.Sprintf(" AND LOWER(mo.modelo) = LOWER($%d)", argIndex)
+		queryArgs = append(queryArgs, model)
+		argIndex++
+	}
+
 	vehicleType := request.GetString("type", "")
 	if vehicleType != "" {
 		query += fmt.Sprintf(" AND v.tipo_veiculo = $%d", argIndex)
@@ -256,11 +830,18 @@ func (s *Server) GetVehiclesAvailable(ctx context.Context, request mcp.CallToolR
 		argIndex++
 	}
 
+	sort := request.GetString("sort", "")
 	orderBy := " ORDER BY v.preco_venda ASC"
-	if request.GetString("sort", "") == "expensive" || maxPrice == 0 {
+	if sort == "expensive" || (sort == "" && maxPrice == 0) {
 		orderBy = " ORDER BY v.preco_venda DESC"
 	}
 
+	nearPrice := request.GetFloat("near_price", 0)
+	if nearPrice > 0 {
+		orderBy = fmt.Sprintf(" ORDER BY ABS(v.preco_venda - $%d) ASC", argIndex)
+		queryArgs = append(queryArgs, nearPrice)
+	}
+
 	query += orderBy + " LIMIT 3"
 
 	rows, err := s.DB.QueryContext(ctx, query, queryArgs...)
@@ -269,39 +850,52 @@ func (s *Server) GetVehiclesAvailable(ctx context.Context, request mcp.CallToolR
 	}
 	defer rows.Close()
 
-	var vehicles []map[string]interface{}
+	vehicles := []Vehicle{}
 	for rows.Next() {
-		var marca, modelo, versao, tipoVeiculo, statusVeiculo, cor, tipoCombustivel string
-		var precoVenda, consumoUrbano, consumoRodoviario, ipvaAnual float64
-		var potenciaCv, anoModelo int
-
-		err := rows.Scan(&marca, &modelo, &versao, &precoVenda, &tipoVeiculo, &statusVeiculo,
-			&consumoUrbano, &consumoRodoviario, &potenciaCv, &ipvaAnual, &anoModelo, &cor, &tipoCombustivel)
+		var v Vehicle
+		err := rows.Scan(&v.ID, &v.Brand, &v.Model, &v.Version, &v.Price, &v.VehicleType, &v.Status,
+			&v.UrbanConsumption, &v.HighwayConsumption, &v.Horsepower, &v.AnnualIPVA, &v.ModelYear, &v.Color, &v.FuelType)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("erro ao escanear linha: %v", err)), nil
 		}
+		vehicles = append(vehicles, v)
+	}
 
-		vehicles = append(vehicles, map[string]interface{}{
-			"marca":              marca,
-			"modelo":             modelo,
-			"versao":             versao,
-			"preco_venda":        precoVenda,
-			"tipo_veiculo":       tipoVeiculo,
-			"status_veiculo":     statusVeiculo,
-			"consumo_urbano":     consumoUrbano,
-			"consumo_rodoviario": consumoRodoviario,
-			"potencia_cv":        potenciaCv,
-			"ipva_anual":         ipvaAnual,
-			"ano_modelo":         anoModelo,
-			"cor":                cor,
-			"tipo_combustivel":   tipoCombustivel,
-		})
+	if err := s.applyCampaignPrices(ctx, vehicles); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := s.applyRecallAlerts(ctx, vehicles); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := s.applyIncentives(ctx, vehicles); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	resultJSON, _ := json.Marshal(vehicles)
 	return mcp.NewToolResultText(string(resultJSON)), nil
 }
 
+func (s *Server) GetVehiclePriceStats(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query := `
+		SELECT
+			COUNT(*),
+			COALESCE(MIN(preco_venda), 0),
+			COALESCE(AVG(preco_venda), 0),
+			COALESCE(MAX(preco_venda), 0)
+		FROM veiculos
+		WHERE status_veiculo = 'Disponivel'
+	`
+
+	var stats VehiclePriceStats
+	err := s.DB.QueryRowContext(ctx, query).Scan(&stats.Count, &stats.MinPrice, &stats.AvgPrice, &stats.MaxPrice)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("erro ao calcular preços: %v", err)), nil
+	}
+
+	resultJSON, _ := json.Marshal(stats)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
 func (s *Server) GetBestFinancing(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	query := `
 		SELECT 
@@ -342,29 +936,15 @@ func (s *Server) GetBestFinancing(ctx context.Context, request mcp.CallToolReque
 	}
 	defer rows.Close()
 
-	var financings []map[string]interface{}
+	financings := []FinancingOption{}
 	for rows.Next() {
-		var banco, tipo, observacoes string
-		var taxaMes, taxaAno, valorEntrada, valorParcela, valorTotal float64
-		var numeroParcelas int
-
-		err := rows.Scan(&banco, &tipo, &taxaMes, &taxaAno, &numeroParcelas,
-			&valorEntrada, &valorParcela, &valorTotal, &observacoes)
+		var f FinancingOption
+		err := rows.Scan(&f.Bank, &f.Type, &f.MonthlyRate, &f.AnnualRate, &f.Installments,
+			&f.DownPayment, &f.Installment, &f.Total, &f.Notes)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("erro ao escanear financiamento: %v", err)), nil
 		}
-
-		financings = append(financings, map[string]interface{}{
-			"banco":         banco,
-			"tipo":          tipo,
-			"taxa_mes":      taxaMes,
-			"taxa_ano":      taxaAno,
-			"parcelas":      numeroParcelas,
-			"valor_entrada": valorEntrada,
-			"valor_parcela": valorParcela,
-			"valor_total":   valorTotal,
-			"observacoes":   observacoes,
-		})
+		financings = append(financings, f)
 	}
 
 	resultJSON, _ := json.Marshal(financings)
@@ -377,51 +957,291 @@ func (s *Server) CalculateFinancing(ctx context.Context, request mcp.CallToolReq
 		return mcp.NewToolResultError("parâmetro 'vehicle_price' é obrigatório"), nil
 	}
 
-	installments, err := request.RequireFloat("installments")
+	installments, errResult := termFrom(request)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	downPayment, tradeIn, errResult := entryFrom(request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	bank := request.GetString("bank", "")
+
+	system, err := finance.ParseSystem(request.GetString("system", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	monthlyRate, bank, campaign, errResult := s.financingRate(ctx, bank, request.GetString("model", ""))
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	financeAmount := vehiclePrice - downPayment
+	if financeAmount <= 0 {
+		return mcp.NewToolResultError("a entrada cobre o valor do veículo; não há valor a financiar"), nil
+	}
+
+	schedule, err := finance.Amortize(system, financeAmount, monthlyRate, installments)
 	if err != nil {
-		return mcp.NewToolResultError("parâmetro 'installments' é obrigatório"), nil
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	result := newFinancingSimulation(vehiclePrice, downPayment, bank, schedule)
+	result.TradeIn = tradeIn
+	result.Campaign = campaign
+	if request.GetBool("include_schedule", false) {
+		result.Schedule = schedule.Installments
 	}
 
-	downPayment := request.GetFloat("down_payment", 0.0)
-	bank := request.GetString("bank", "Itaú Unibanco")
+	resultJSON, _ := json.Marshal(result)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+// financingOffer é a condição aprovada usada nas simulações, com a taxa mensal
+// em fração.
+type financingOffer struct {
+	Bank         string
+	MonthlyRate  float64
+	Installments int
+}
 
-	var interestRate float64 = 0.0427
+// lookupOffer busca a condição aprovada de menor taxa do tipo de financiamento,
+// restrita ao banco quando informado.
+func (s *Server) lookupOffer(ctx context.Context, bank, financingType string) (financingOffer, error) {
 	query := `
-		SELECT taxa_juros_ano 
+		SELECT taxa_juros_mes, taxa_juros_ano, banco_financiadora, numero_parcelas
 		FROM financiamentos 
-		WHERE banco_financiadora = $1 AND aprovado = true 
-		ORDER BY taxa_juros_ano ASC 
+		WHERE ($1 = '' OR banco_financiadora = $1) AND tipo_financiamento = $2 AND aprovado = true 
+		ORDER BY COALESCE(taxa_juros_mes, taxa_juros_ano / 12) ASC 
 		LIMIT 1
 	`
 
-	row := s.DB.QueryRowContext(ctx, query, bank)
-	var rate float64
-	if err := row.Scan(&rate); err == nil {
-		interestRate = rate / 100
+	var offer financingOffer
+	var storedMonthly, storedAnnual sql.NullFloat64
+	var installments sql.NullInt64
+	err := s.DB.QueryRowContext(ctx, query, bank, financingType).Scan(&storedMonthly, &storedAnnual, &offer.Bank, &installments)
+	if err == sql.ErrNoRows {
+		if bank != "" {
+			return offer, fmt.Errorf("nenhum financiamento %s aprovado encontrado para o banco %s", financingType, bank)
+		}
+		return offer, fmt.Errorf("nenhum financiamento %s aprovado encontrado", financingType)
+	}
+	if err != nil {
+		return offer, fmt.Errorf("erro ao buscar taxa de juros: %w", err)
 	}
 
-	financeAmount := vehiclePrice - downPayment
-	monthlyRate := interestRate / 12
-
-	var monthlyPayment float64
-	if monthlyRate > 0 {
-		monthlyPayment = financeAmount * (monthlyRate * mathPow(1+monthlyRate, installments)) / (mathPow(1+monthlyRate, installments) - 1)
+	// As taxas são guardadas em porcentagem. A mensal do banco tem precedência;
+	// sem ela, a anual é convertida por capitalização composta.
+	if storedMonthly.Valid {
+		offer.MonthlyRate = storedMonthly.Float64 / 100
 	} else {
-		monthlyPayment = financeAmount / installments
+		offer.MonthlyRate = finance.MonthlyFromAnnual(storedAnnual.Float64 / 100)
 	}
+	offer.Installments = int(installments.Int64)
+	return offer, nil
+}
 
-	totalAmount := monthlyPayment * installments
+// lookupMonthlyRate busca a taxa mensal (em fração) de CDC do banco informado
+// ou, sem banco, a menor taxa entre os CDCs aprovados.
+func (s *Server) lookupMonthlyRate(ctx context.Context, bank string) (float64, string, *mcp.CallToolResult) {
+	offer, err := s.lookupOffer(ctx, bank, finance.ModeCDC)
+	if err != nil {
+		return 0, "", mcp.NewToolResultError(err.Error())
+	}
+	return offer.MonthlyRate, offer.Bank, nil
+}
 
-	result := map[string]interface{}{
-		"valor_veiculo":      vehiclePrice,
-		"valor_entrada":      downPayment,
-		"valor_financiado":   financeAmount,
-		"numero_parcelas":    int(installments),
-		"valor_parcela":      monthlyPayment,
-		"valor_total":        totalAmount,
-		"taxa_juros_ano":     interestRate * 100,
-		"banco_financiadora": bank,
+// termFrom lê o prazo obrigatório em parcelas, que deve ser um número inteiro
+// entre finance.MinTerm e finance.MaxTerm.
+func termFrom(request mcp.CallToolRequest) (int, *mcp.CallToolResult) {
+	installments, err := request.RequireFloat("installments")
+	if err != nil {
+		return 0, mcp.NewToolResultError("parâmetro 'installments' é obrigatório")
+	}
+	if installments != math.Trunc(installments) || installments < finance.MinTerm || installments > finance.MaxTerm {
+		return 0, mcp.NewToolResultError(fmt.Sprintf("o prazo deve ser um número inteiro de parcelas entre %d e %d", finance.MinTerm, finance.MaxTerm))
+	}
+	return int(installments), nil
+}
+
+// entryFrom soma à entrada em dinheiro o valor do usado dado na troca.
+func entryFrom(request mcp.CallToolRequest) (entry, tradeIn float64, errResult *mcp.CallToolResult) {
+	entry = request.GetFloat("down_payment", 0)
+	tradeIn = request.GetFloat("trade_in_value", 0)
+	if entry < 0 || tradeIn < 0 {
+		return 0, 0, mcp.NewToolResultError("a entrada e o valor da troca não podem ser negativos")
+	}
+	return entry + tradeIn, tradeIn, nil
+}
+
+func newFinancingSimulation(vehiclePrice, downPayment float64, bank string, schedule *finance.Schedule) FinancingSimulation {
+	return FinancingSimulation{
+		VehiclePrice:     vehiclePrice,
+		DownPayment:      downPayment,
+		FinancedAmount:   schedule.Principal,
+		Installments:     len(schedule.Installments),
+		System:           schedule.System,
+		Installment:      schedule.First().Payment,
+		FirstInstallment: schedule.First().Payment,
+		LastInstallment:  schedule.Last().Payment,
+		Total:            schedule.TotalPaid,
+		TotalInterest:    schedule.TotalInterest,
+		AnnualRate:       finance.AnnualFromMonthly(schedule.MonthlyRate) * 100,
+		MonthlyRate:      schedule.MonthlyRate * 100,
+		Bank:             bank,
+	}
+}
+
+// solvableFields são os valores que SolveFinancing pode calcular; exatamente um
+// deles deve ficar de fora da requisição.
+var solvableFields = []string{"vehicle_price", "down_payment", "installment", "installments"}
+
+func (s *Server) SolveFinancing(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	var missing []string
+	for _, name := range solvableFields {
+		if _, ok := args[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) != 1 {
+		return mcp.NewToolResultError(fmt.Sprintf(
+			"informe exatamente três entre vehicle_price, down_payment, installment e installments (faltando: %s)",
+			strings.Join(missing, ", "))), nil
+	}
+
+	vehiclePrice := request.GetFloat("vehicle_price", 0)
+	downPayment, tradeIn, errResult := entryFrom(request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	payment := request.GetFloat("installment", 0)
+	months := 0
+	if missing[0] != "installments" {
+		months, errResult = termFrom(request)
+		if errResult != nil {
+			return errResult, nil
+		}
+	}
+	if vehiclePrice < 0 || payment < 0 {
+		return mcp.NewToolResultError("preço, entrada e parcela não podem ser negativos"), nil
+	}
+
+	monthlyRate, bank, campaign, errResult := s.financingRate(ctx, request.GetString("bank", ""), request.GetString("model", ""))
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	var note string
+	switch missing[0] {
+	case "vehicle_price":
+		principal, err := finance.SolvePrincipal(payment, monthlyRate, months)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		vehiclePrice = downPayment + principal
+
+	case "down_payment":
+		principal, err := finance.SolvePrincipal(payment, monthlyRate, months)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		// A entrada calculada inclui a troca; só o que faltar sai em dinheiro.
+		downPayment = finance.Round(vehiclePrice - principal)
+		switch {
+		case downPayment >= tradeIn:
+		case tradeIn > 0:
+			downPayment = tradeIn
+			note = fmt.Sprintf("a parcela de R$ %.2f cobre o veículo sem entrada em dinheiro; a simulação usa só a troca como entrada", payment)
+		default:
+			downPayment = 0
+			note = fmt.Sprintf("a parcela de R$ %.2f cobre o veículo sem entrada; a simulação usa entrada zero", payment)
+		}
+
+	case "installments":
+		var err error
+		months, err = finance.SolveTerm(vehiclePrice-downPayment, monthlyRate, payment)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	if downPayment >= vehiclePrice {
+		return mcp.NewToolResultError("a entrada cobre o valor do veículo; não há valor a financiar"), nil
+	}
+
+	schedule, err := finance.Amortize(finance.Price, vehiclePrice-downPayment, monthlyRate, months)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	result := FinancingSolution{
+		Solved:              missing[0],
+		FinancingSimulation: newFinancingSimulation(vehiclePrice, downPayment, bank, schedule),
+		Note:                note,
+	}
+	result.TradeIn = tradeIn
+	result.Campaign = campaign
+
+	resultJSON, _ := json.Marshal(result)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+func (s *Server) CalculateCET(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	vehiclePrice, err := request.RequireFloat("vehicle_price")
+	if err != nil {
+		return mcp.NewToolResultError("parâmetro 'vehicle_price' é obrigatório"), nil
+	}
+
+	installments, errResult := termFrom(request)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	system, err := finance.ParseSystem(request.GetString("system", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	downPayment, tradeIn, errResult := entryFrom(request)
+	if errResult != nil {
+		return errResult, nil
 	}
+	released := vehiclePrice - downPayment
+	if released <= 0 {
+		return mcp.NewToolResultError("a entrada cobre o valor do veículo; não há valor a financiar"), nil
+	}
+
+	monthlyRate, bank, campaign, errResult := s.financingRate(ctx, request.GetString("bank", ""), request.GetString("model", ""))
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	fees := finance.Fees{
+		TAC:           request.GetFloat("tac", 0),
+		Registration:  request.GetFloat("registration_fee", 0),
+		InsuranceRate: request.GetFloat("insurance_rate", 0) / 100,
+		Financed:      request.GetBool("finance_fees", true),
+	}
+	if fees.TAC < 0 || fees.Registration < 0 || fees.InsuranceRate < 0 {
+		return mcp.NewToolResultError("tarifas e seguro não podem ser negativos"), nil
+	}
+
+	cet, err := finance.ComputeCET(system, released, monthlyRate, installments, fees)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	result := CETSimulation{
+		FinancingSimulation: newFinancingSimulation(vehiclePrice, downPayment, bank, cet.Schedule),
+		Costs:               *cet,
+		CETMonthly:          cet.MonthlyRate * 100,
+		CETAnnual:           cet.AnnualRate * 100,
+	}
+	result.TradeIn = tradeIn
+	result.Campaign = campaign
 
 	resultJSON, _ := json.Marshal(result)
 	return mcp.NewToolResultText(string(resultJSON)), nil
@@ -441,13 +1261,23 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-func mathPow(base, exp float64) float64 {
-	if exp == 0 {
-		return 1
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
 	}
-	result := 1.0
-	for i := 0; i < int(exp); i++ {
-		result *= base
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && value > 0 {
+		return value
 	}
-	return result
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
 }
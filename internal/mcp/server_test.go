@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func toolRequest(args map[string]any) mcp.CallToolRequest {
+	var request mcp.CallToolRequest
+	request.Params.Arguments = args
+	return request
+}
+
+func TestTermFrom(t *testing.T) {
+	tests := []struct {
+		installments any
+		want         int
+		valid        bool
+	}{
+		{float64(60), 60, true},
+		{float64(6), 6, true},
+		{float64(84), 84, true},
+		{float64(5), 0, false},
+		{float64(85), 0, false},
+		{60.5, 0, false},
+		{1e12, 0, false},
+		{-48.0, 0, false},
+		{nil, 0, false},
+	}
+	for _, tt := range tests {
+		args := map[string]any{}
+		if tt.installments != nil {
+			args["installments"] = tt.installments
+		}
+		got, errResult := termFrom(toolRequest(args))
+		if (errResult == nil) != tt.valid || got != tt.want {
+			t.Errorf("termFrom(%v) = %d, erro %v; esperado %d, válido %v", tt.installments, got, errResult != nil, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestMaintenancePlanRejectsHorizon(t *testing.T) {
+	// Os limites são conferidos antes de qualquer consulta ao banco.
+	s := &Server{}
+	for _, args := range []map[string]any{
+		{"years": float64(11)},
+		{"km": 1e300},
+		{"km": float64(150001), "annual_km": float64(15000)},
+		{"km": float64(1000), "annual_km": 1e-300},
+		{"km": float64(-1)},
+	} {
+		result, err := s.GetMaintenancePlan(context.Background(), toolRequest(args))
+		if err != nil || result == nil || !result.IsError {
+			t.Errorf("GetMaintenancePlan(%v) deveria ser recusado", args)
+		}
+	}
+}
+
+func TestSearchVehiclesRejectsInvalidFilters(t *testing.T) {
+	s := &Server{}
+	for _, args := range []map[string]any{
+		{"min_year": float64(0)},
+		{"max_price": float64(-50000)},
+		{"max_power": "muita"},
+		{"max_km": float64(-1)},
+	} {
+		result, err := s.SearchVehicles(context.Background(), toolRequest(args))
+		if err != nil || result == nil || !result.IsError {
+			t.Errorf("SearchVehicles(%v) deveria ser recusado", args)
+		}
+	}
+}
+
+func TestContainsPattern(t *testing.T) {
+	for text, want := range map[string]string{
+		"Câmera de Ré": `%Câmera de Ré%`,
+		"100%":         `%100\%%`,
+		"som_premium":  `%som\_premium%`,
+		`a\b`:          `%a\\b%`,
+	} {
+		if got := containsPattern(text); got != want {
+			t.Errorf("containsPattern(%q) = %q, esperado %q", text, got, want)
+		}
+	}
+}
+
+func TestSolveFinancingValidatesTerm(t *testing.T) {
+	// O prazo é conferido antes de buscar a taxa no banco.
+	s := &Server{}
+	for _, installments := range []float64{36.7, 5, 85} {
+		args := map[string]any{"vehicle_price": float64(80000), "down_payment": float64(20000), "installments": installments}
+		result, err := s.SolveFinancing(context.Background(), toolRequest(args))
+		if err != nil || result == nil || !result.IsError {
+			t.Errorf("SolveFinancing com %v parcelas deveria ser recusado", installments)
+		}
+	}
+}
+
+func TestOfferTerm(t *testing.T) {
+	tests := []struct {
+		stored, requested, want int
+		valid                   bool
+	}{
+		{48, 0, 48, true},
+		{48, 24, 24, true},
+		{0, 0, 0, false},
+		{120, 0, 0, false},
+		{3, 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := offerTerm(financingOffer{Bank: "Banco", Installments: tt.stored}, tt.requested)
+		if (err == nil) != tt.valid || got != tt.want {
+			t.Errorf("offerTerm(%d, %d) = %d, %v; esperado %d", tt.stored, tt.requested, got, err, tt.want)
+		}
+	}
+}
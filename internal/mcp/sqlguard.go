@@ -0,0 +1,210 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Motivos de rejeição devolvidos ao modelo em queryRejection.Code.
+const (
+	rejectEmpty              = "consulta_vazia"
+	rejectNotSelect          = "apenas_select"
+	rejectMultipleStatements = "multiplas_instrucoes"
+	rejectForbiddenKeyword   = "palavra_proibida"
+	rejectUnterminated       = "consulta_incompleta"
+	rejectTimeout            = "tempo_esgotado"
+	rejectReadOnly           = "somente_leitura"
+)
+
+// forbiddenKeywords não podem aparecer fora de literais, nem dentro de CTEs.
+var forbiddenKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true,
+	"DROP": true, "ALTER": true, "CREATE": true, "TRUNCATE": true,
+	"GRANT": true, "REVOKE": true, "COPY": true, "CALL": true,
+	"DO": true, "LOCK": true, "VACUUM": true, "SET": true, "INTO": true,
+}
+
+// queryRejection é serializada no texto do erro para que o modelo saiba por
+// que a consulta foi recusada e como corrigi-la.
+type queryRejection struct {
+	Code   string `json:"erro"`
+	Reason string `json:"motivo"`
+	Hint   string `json:"dica"`
+}
+
+func (r queryRejection) Error() string {
+	return r.Reason
+}
+
+func (r queryRejection) toolResult() *mcp.CallToolResult {
+	text, _ := json.Marshal(r)
+	return mcp.NewToolResultError(string(text))
+}
+
+// validateReadOnlyQuery aceita uma única instrução SELECT (ou WITH ... SELECT).
+// Comentários e literais são ignorados na análise. A execução ainda acontece
+// numa transação READ ONLY, que é a garantia final.
+func validateReadOnlyQuery(query string) error {
+	code, err := stripLiterals(query)
+	if err != nil {
+		return err
+	}
+
+	code = strings.TrimSpace(code)
+	code = strings.TrimSpace(strings.TrimSuffix(code, ";"))
+	if code == "" {
+		return queryRejection{
+			Code:   rejectEmpty,
+			Reason: "a consulta está vazia",
+			Hint:   "envie uma instrução SELECT",
+		}
+	}
+
+	if strings.Contains(code, ";") {
+		return queryRejection{
+			Code:   rejectMultipleStatements,
+			Reason: "apenas uma instrução por chamada é permitida",
+			Hint:   "remova o ';' intermediário e envie cada consulta em uma chamada separada",
+		}
+	}
+
+	words := strings.FieldsFunc(strings.ToUpper(code), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	if len(words) == 0 || (words[0] != "SELECT" && words[0] != "WITH") {
+		first := ""
+		if len(words) > 0 {
+			first = words[0]
+		}
+		return queryRejection{
+			Code:   rejectNotSelect,
+			Reason: fmt.Sprintf("apenas consultas SELECT são permitidas (recebido: %s)", first),
+			Hint:   "reescreva a consulta como SELECT; o banco é somente leitura",
+		}
+	}
+
+	for _, word := range words {
+		if forbiddenKeywords[word] {
+			return queryRejection{
+				Code:   rejectForbiddenKeyword,
+				Reason: fmt.Sprintf("a palavra-chave %s não é permitida em consultas somente leitura", word),
+				Hint:   "use apenas SELECT, sem alterar dados, travar linhas ou criar tabelas",
+			}
+		}
+	}
+
+	return nil
+}
+
+// stripLiterals troca comentários por espaço, remove o conteúdo de strings e
+// blocos $tag$ e tira as aspas de identificadores, para que ';' e palavras-chave
+// dentro de literais não sejam confundidos com código.
+func stripLiterals(query string) (string, error) {
+	unterminated := queryRejection{
+		Code:   rejectUnterminated,
+		Reason: "a consulta tem aspas ou comentário sem fechamento",
+		Hint:   "confira aspas simples, aspas duplas e comentários /* */",
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(query); {
+		switch {
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return out.String(), nil
+			}
+			out.WriteByte(' ')
+			i += end + 1
+
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return "", unterminated
+			}
+			out.WriteByte(' ')
+			i += end + 4
+
+		case query[i] == '\'' || query[i] == '"':
+			quote := query[i]
+			escapes := quote == '\'' && isEscapeStringPrefix(query, i)
+			j := i + 1
+			for {
+				if j >= len(query) {
+					return "", unterminated
+				}
+				if escapes && query[j] == '\\' {
+					j += 2
+					continue
+				}
+				if query[j] == quote {
+					if j+1 < len(query) && query[j+1] == quote {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if quote == '"' {
+				// Identificadores entre aspas continuam visíveis para a política de acesso.
+				out.WriteString(strings.ReplaceAll(query[i+1:j], `""`, `"`))
+			} else {
+				out.WriteString("''")
+			}
+			i = j + 1
+
+		case query[i] == '$':
+			tag, ok := dollarTag(query[i:])
+			if !ok {
+				out.WriteByte(query[i])
+				i++
+				continue
+			}
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				return "", unterminated
+			}
+			out.WriteString("''")
+			i += len(tag) + end + len(tag)
+
+		default:
+			out.WriteByte(query[i])
+			i++
+		}
+	}
+	return out.String(), nil
+}
+
+// isEscapeStringPrefix indica se a aspa em i abre uma string E'...', onde a
+// barra invertida escapa o caractere seguinte.
+func isEscapeStringPrefix(query string, i int) bool {
+	if i == 0 || (query[i-1] != 'E' && query[i-1] != 'e') {
+		return false
+	}
+	if i == 1 {
+		return true
+	}
+	c := query[i-2]
+	return !(c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9'))
+}
+
+// dollarTag reconhece o início de um bloco $$ ou $tag$; parâmetros como $1 não são tags.
+func dollarTag(s string) (string, bool) {
+	for j := 1; j < len(s); j++ {
+		c := s[j]
+		if c == '$' {
+			return s[:j+1], true
+		}
+		isLetter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		isDigit := c >= '0' && c <= '9'
+		if !isLetter && !(isDigit && j > 1) {
+			return "", false
+		}
+	}
+	return "", false
+}
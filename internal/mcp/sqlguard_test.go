@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateReadOnlyQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		code  string // "" quando a consulta deve ser aceita
+	}{
+		{"select simples", "SELECT * FROM veiculos", ""},
+		{"with select", "WITH x AS (SELECT 1 AS n) SELECT n FROM x", ""},
+		{"ponto e vírgula final", "SELECT 1;", ""},
+		{"vazia", "   ", rejectEmpty},
+		{"só ponto e vírgula", " ; ", rejectEmpty},
+
+		{"duas instruções", "SELECT 1; SELECT 2", rejectMultipleStatements},
+		{"select seguido de drop", "SELECT 1; DROP TABLE veiculos", rejectMultipleStatements},
+		{"ponto e vírgula em literal", "SELECT ';' AS separador", ""},
+
+		{"palavra em comentário de linha", "SELECT 1 -- ; DELETE FROM veiculos\n", ""},
+		{"palavra em comentário de bloco", "SELECT /* DROP TABLE veiculos; */ 1", ""},
+		{"comentário antes de delete", "-- consulta\nDELETE FROM veiculos", rejectNotSelect},
+		{"bloco antes de delete", "/* SELECT */ DELETE FROM veiculos", rejectNotSelect},
+		{"comentário sem fechamento", "SELECT 1 /* DELETE", rejectUnterminated},
+		{"comentário de linha no fim", "SELECT 1 -- sem quebra de linha", ""},
+
+		{"dólar com ponto e vírgula", "SELECT $$; DELETE FROM veiculos$$", ""},
+		{"dólar com tag", "SELECT $tag$ DROP TABLE veiculos $tag$ AS texto", ""},
+		{"dólar sem fechamento", "SELECT $$; DELETE FROM veiculos", rejectUnterminated},
+		{"parâmetro não é tag", "SELECT $1", ""},
+		{"string E com aspa escapada", `SELECT E'\'; DELETE FROM veiculos; --'`, ""},
+		{"barra fora de string E fecha a string", `SELECT '\'; DELETE FROM veiculos; --'`, rejectMultipleStatements},
+		{"aspas duplicadas", "SELECT 'it''s; DROP'", ""},
+		{"aspa sem fechamento", "SELECT 'DELETE", rejectUnterminated},
+
+		{"cte com delete", "WITH x AS (DELETE FROM veiculos RETURNING *) SELECT * FROM x", rejectForbiddenKeyword},
+		{"cte com update", "WITH x AS (UPDATE veiculos SET preco_venda = 0 RETURNING *) SELECT 1", rejectForbiddenKeyword},
+		{"cte com insert", "WITH x AS (INSERT INTO marcas VALUES (1) RETURNING *) SELECT 1", rejectForbiddenKeyword},
+		{"select into", "SELECT * INTO copia FROM veiculos", rejectForbiddenKeyword},
+		{"select for update", "SELECT * FROM veiculos FOR UPDATE", rejectForbiddenKeyword},
+		{"copy", "COPY veiculos TO STDOUT", rejectNotSelect},
+		{"copy dentro de with", "WITH x AS (SELECT 1) COPY x TO STDOUT", rejectForbiddenKeyword},
+		{"set", "SET search_path = public", rejectNotSelect},
+		{"set após select", "SELECT 1 FROM veiculos WHERE true; SET ROLE postgres", rejectMultipleStatements},
+		{"identificador entre aspas continua visível", `SELECT 1 FROM "DELETE"`, rejectForbiddenKeyword},
+		{"palavra proibida em minúsculas", "with x as (delete from veiculos returning *) select 1", rejectForbiddenKeyword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateReadOnlyQuery(tt.query)
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("consulta deveria ser aceita: %v", err)
+				}
+				return
+			}
+			var rejection queryRejection
+			if !errors.As(err, &rejection) {
+				t.Fatalf("esperado queryRejection %s, veio %v", tt.code, err)
+			}
+			if rejection.Code != tt.code || rejection.Reason == "" || rejection.Hint == "" {
+				t.Errorf("rejeição = %+v, esperado código %s com motivo e dica", rejection, tt.code)
+			}
+		})
+	}
+}
+
+func TestStripLiterals(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT 'a;b'", "SELECT ''"},
+		{"SELECT $x$ a $x$, 1", "SELECT '', 1"},
+		{`SELECT E'a\'b', 1`, "SELECT E'', 1"},
+		{"SELECT 1 -- fim\n, 2", "SELECT 1  , 2"},
+		{"SELECT /* a */ 1", "SELECT   1"},
+		{`SELECT "Nome ""x"""`, `SELECT Nome "x"`},
+	}
+	for _, tt := range tests {
+		got, err := stripLiterals(tt.query)
+		if err != nil || got != tt.want {
+			t.Errorf("stripLiterals(%q) = %q, %v; esperado %q", tt.query, got, err, tt.want)
+		}
+	}
+}
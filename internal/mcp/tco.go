@@ -0,0 +1,242 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"mcp-gemini-go/internal/finance"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Estimativas usadas quando o modelo não tem dados de seguro, manutenção ou
+// valorização cadastrados, em fração do valor do veículo ao ano.
+const (
+	defaultDepreciationRate = 0.12
+	defaultInsuranceRate    = 0.04
+	defaultMaintenanceRate  = 0.015
+)
+
+const (
+	sourceDatabase = "cadastro"
+	sourceEstimate = "estimativa"
+)
+
+// vehicleRecord é o veículo com os dados de custo usados pelas ferramentas de
+// posse, que não aparecem nas listagens.
+type vehicleRecord struct {
+	Vehicle
+	ModelID   int
+	Km        float64
+	Licensing float64
+}
+
+// loadVehicle busca o veículo pelo id ou, sem id, o mais barato da marca, do
+// modelo e da versão (trecho do nome), preferindo os disponíveis.
+func (s *Server) loadVehicle(ctx context.Context, id int, brand, model, version string) (*vehicleRecord, error) {
+	if id <= 0 && model == "" {
+		return nil, fmt.Errorf("informe vehicle_id ou model")
+	}
+
+	query := `
+		SELECT
+			v.id_veiculos,
+			v.id_modelos,
+			m.marca,
+			mo.modelo,
+			COALESCE(v.versao, ''),
+			v.preco_venda,
+			v.tipo_veiculo,
+			v.status_veiculo,
+			COALESCE(v.consumo_urbano, 0),
+			COALESCE(v.consumo_rodoviario, 0),
+			COALESCE(v.potencia_cv, 0),
+			COALESCE(v.ipva_anual, 0),
+			v.ano_modelo,
+			COALESCE(v.cor, ''),
+			COALESCE(v.tipo_combustivel, ''),
+			COALESCE(v.quilometragem, 0),
+			COALESCE(v.licenciamento_anual, 0)
+		FROM veiculos v
+		JOIN modelos mo ON v.id_modelos = mo.id_modelos
+		JOIN marcas m ON mo.id_marcas = m.id_marcas
+		WHERE ($1::int = 0 OR v.id_veiculos = $1)
+		AND ($2 = '' OR LOWER(m.marca) = LOWER($2))
+		AND ($3 = '' OR LOWER(mo.modelo) = LOWER($3))
+		AND ($4 = '' OR v.versao ILIKE $4)
+		ORDER BY (v.status_veiculo = 'Disponivel') DESC, v.preco_venda ASC
+		LIMIT 1
+	`
+
+	versionPattern := ""
+	if version != "" {
+		versionPattern = containsPattern(version)
+	}
+
+	var v vehicleRecord
+	err := s.DB.QueryRowContext(ctx, query, id, brand, model, versionPattern).Scan(
+		&v.ID, &v.ModelID, &v.Brand, &v.Model, &v.Version, &v.Price, &v.VehicleType, &v.Status,
+		&v.UrbanConsumption, &v.HighwayConsumption, &v.Horsepower, &v.AnnualIPVA, &v.ModelYear,
+		&v.Color, &v.FuelType, &v.Km, &v.Licensing)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("veículo não encontrado")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar veículo: %w", err)
+	}
+	return &v, nil
+}
+
+// depreciationRate é a taxa anual da curva ajustada ao histórico do modelo.
+// Sem histórico devolve a taxa padrão e found falso.
+func (s *Server) depreciationRate(ctx context.Context, modelID int) (rate float64, found bool, err error) {
+	fit, err := s.depreciationFit(ctx, modelID)
+	if err != nil {
+		return 0, false, err
+	}
+	if fit == nil {
+		return defaultDepreciationRate, false, nil
+	}
+	return fit.Rate, true, nil
+}
+
+type tcoResult struct {
+	Vehicle     Vehicle                   `json:"veiculo"`
+	Years       int                       `json:"anos"`
+	AnnualKm    float64                   `json:"km_anual"`
+	FuelPrice   float64                   `json:"preco_combustivel"`
+	Profile     driverProfile             `json:"perfil_condutor"`
+	Breakdown   []finance.TCOYear         `json:"custos_por_ano"`
+	Total       float64                   `json:"custo_total"`
+	MonthlyCost float64                   `json:"custo_mensal_medio"`
+	ResaleValue float64                   `json:"valor_revenda"`
+	Sources     map[string]string         `json:"fontes"`
+	Maintenance []finance.MaintenanceItem `json:"itens_manutencao"`
+	Notes       []string                  `json:"observacoes,omitempty"`
+}
+
+// CalculateTCO projeta o custo total de posse: IPVA, licenciamento, seguro,
+// manutenção, combustível e depreciação, com o valor de revenda ao final.
+func (s *Server) CalculateTCO(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	fuelPrice, err := request.RequireFloat("fuel_price")
+	if err != nil || fuelPrice <= 0 {
+		return mcp.NewToolResultError("parâmetro 'fuel_price' é obrigatório e deve ser maior que zero"), nil
+	}
+
+	years := request.GetInt("years", 5)
+	if years < 1 || years > 10 {
+		return mcp.NewToolResultError("o horizonte deve ficar entre 1 e 10 anos"), nil
+	}
+	annualKm := request.GetFloat("annual_km", 15000)
+	if annualKm <= 0 {
+		return mcp.NewToolResultError("a quilometragem anual deve ser maior que zero"), nil
+	}
+	urbanShare := request.GetFloat("urban_percent", 60) / 100
+	if urbanShare < 0 || urbanShare > 1 {
+		return mcp.NewToolResultError("o percentual de uso urbano deve ficar entre 0 e 100"), nil
+	}
+
+	vehicle, err := s.loadVehicle(ctx, request.GetInt("vehicle_id", 0), request.GetString("brand", ""), request.GetString("model", ""), "")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	profile := driverProfileFrom(request)
+	result := tcoResult{
+		Vehicle:   vehicle.Vehicle,
+		Years:     years,
+		AnnualKm:  annualKm,
+		FuelPrice: fuelPrice,
+		Profile:   profile,
+		Sources:   map[string]string{},
+	}
+
+	costs := finance.OwnershipCosts{
+		Price:     vehicle.Price,
+		StartKm:   vehicle.Km,
+		AnnualKm:  annualKm,
+		IPVA:      vehicle.AnnualIPVA,
+		Licensing: vehicle.Licensing,
+	}
+	result.Sources["ipva"] = sourceDatabase
+	result.Sources["licenciamento"] = sourceDatabase
+
+	incentives, note, err := s.incentivesFor(ctx, vehicle.ID, profile.City)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if note != "" {
+		result.Notes = append(result.Notes, note)
+	}
+	if ipva, applied := bestIPVA(costs.IPVA, incentives); applied != nil {
+		costs.IPVA = ipva
+		result.Notes = append(result.Notes, fmt.Sprintf("IPVA de R$ %.2f com incentivo fiscal: %s", ipva, applied.Description))
+	}
+
+	costs.Fuel, err = finance.AnnualFuelCost(annualKm, urbanShare, vehicle.UrbanConsumption, vehicle.HighwayConsumption, fuelPrice)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("não é possível calcular o combustível de %s %s: %v", vehicle.Brand, vehicle.Model, err)), nil
+	}
+	result.Sources["combustivel"] = sourceDatabase
+
+	insurance, err := s.estimateInsurance(ctx, vehicle, profile, "")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	switch {
+	case insurance == nil:
+		costs.Insurance = finance.Round(vehicle.Price * defaultInsuranceRate)
+		result.Sources["seguro"] = sourceEstimate
+		result.Notes = append(result.Notes, fmt.Sprintf("sem cotação de seguro para o perfil; estimado em %.0f%% do valor do veículo", defaultInsuranceRate*100))
+	case insurance.Match == matchExact:
+		costs.Insurance = insurance.Average
+		result.Sources["seguro"] = sourceDatabase
+	default:
+		costs.Insurance = insurance.Average
+		result.Sources["seguro"] = sourceEstimate
+		result.Notes = append(result.Notes, fmt.Sprintf("seguro estimado por %s (veja estimate_insurance)", strings.ReplaceAll(insurance.Match, "_", " ")))
+	}
+
+	costs.Maintenance, err = s.maintenanceItems(ctx, vehicle.ModelID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(costs.Maintenance) > 0 {
+		result.Sources["manutencao"] = sourceDatabase
+	} else {
+		costs.Maintenance = []finance.MaintenanceItem{{
+			Name:           "Manutenção estimada",
+			IntervalMonths: 12,
+			Cost:           finance.Round(vehicle.Price * defaultMaintenanceRate),
+		}}
+		result.Sources["manutencao"] = sourceEstimate
+		result.Notes = append(result.Notes, fmt.Sprintf("sem plano de manutenção cadastrado; estimado em %.1f%% do valor do veículo ao ano", defaultMaintenanceRate*100))
+	}
+	result.Maintenance = costs.Maintenance
+
+	rate, found, err := s.depreciationRate(ctx, vehicle.ModelID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	costs.DepreciationRate = rate
+	if found {
+		result.Sources["depreciacao"] = sourceDatabase
+	} else {
+		result.Sources["depreciacao"] = sourceEstimate
+		result.Notes = append(result.Notes, fmt.Sprintf("sem histórico de valorização do modelo; depreciação estimada em %.0f%% ao ano", defaultDepreciationRate*100))
+	}
+
+	result.Breakdown = finance.ProjectTCO(costs, years)
+	for _, year := range result.Breakdown {
+		result.Total += year.Total
+	}
+	result.Total = finance.Round(result.Total)
+	result.MonthlyCost = finance.Round(result.Total / float64(years*12))
+	result.ResaleValue = result.Breakdown[len(result.Breakdown)-1].VehicleValue
+
+	resultJSON, _ := json.Marshal(result)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
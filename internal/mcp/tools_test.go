@@ -0,0 +1,251 @@
+package mcp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestHandlersReadOnlyDeclaredArguments percorre as ferramentas registradas em
+// Initialize e confere que todo argumento lido pelo nome literal, no handler ou
+// nas funções auxiliares que recebem a requisição, está declarado no schema.
+// Nomes vindos de listas (os filtros de search_vehicles, por exemplo) ficam de
+// fora da verificação.
+func TestHandlersReadOnlyDeclaredArguments(t *testing.T) {
+	s := NewServer()
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	scanner, err := newArgumentScanner(".")
+	if err != nil {
+		t.Fatalf("erro ao analisar o pacote: %v", err)
+	}
+
+	if len(s.tools) == 0 {
+		t.Fatal("nenhuma ferramenta registrada")
+	}
+	for _, tool := range s.tools {
+		t.Run(tool.Tool.Name, func(t *testing.T) {
+			handler := handlerName(tool.Handler)
+			reads, err := scanner.reads(handler)
+			if err != nil {
+				t.Fatalf("%s: %v", handler, err)
+			}
+			for _, name := range reads {
+				if _, declared := tool.Tool.InputSchema.Properties[name]; !declared {
+					t.Errorf("%s lê o argumento '%s', que não está declarado em %s", handler, name, tool.Tool.Name)
+				}
+			}
+		})
+	}
+}
+
+// O scanner precisa seguir as funções auxiliares, senão o teste acima passaria
+// sem conferir nada.
+func TestArgumentScannerFollowsHelpers(t *testing.T) {
+	scanner, err := newArgumentScanner(".")
+	if err != nil {
+		t.Fatalf("erro ao analisar o pacote: %v", err)
+	}
+
+	tests := []struct {
+		handler string
+		want    []string
+	}{
+		{"Server.CalculateFinancing", []string{"installments", "down_payment", "trade_in_value"}},
+		{"Server.EstimateInsurance", []string{"driver_age", "license_years"}},
+		{"Server.SolveFinancing", []string{"vehicle_price", "installment"}},
+		{"Server.CompareVehicles", []string{"vehicle_ids", "vehicles", "city"}},
+		{"Server.SearchVehicles", []string{"safety", "features"}},
+	}
+	for _, tt := range tests {
+		reads, err := scanner.reads(tt.handler)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.handler, err)
+		}
+		for _, name := range tt.want {
+			if !contains(reads, name) {
+				t.Errorf("%s: leitura de '%s' não encontrada em %v", tt.handler, name, reads)
+			}
+		}
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// handlerName converte o nome do method value registrado, como
+// "mcp-gemini-go/internal/mcp.(*Server).GetSchema-fm", em "Server.GetSchema".
+func handlerName(handler interface{}) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	name = strings.TrimSuffix(name[strings.LastIndex(name, "/")+1:], "-fm")
+	name = strings.TrimPrefix(name, "mcp.")
+	return strings.NewReplacer("(*", "", ")", "").Replace(name)
+}
+
+// argumentScanner encontra, no código do pacote, as leituras de argumentos com
+// nome literal feitas sobre parâmetros do tipo mcp.CallToolRequest.
+type argumentScanner struct {
+	funcs map[string]*ast.FuncDecl
+}
+
+func newArgumentScanner(dir string) (*argumentScanner, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := &argumentScanner{funcs: map[string]*ast.FuncDecl{}}
+	fset := token.NewFileSet()
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				scanner.funcs[funcKey(fn)] = fn
+			}
+		}
+	}
+	return scanner, nil
+}
+
+func funcKey(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+	receiver := decl.Recv.List[0].Type
+	if star, ok := receiver.(*ast.StarExpr); ok {
+		receiver = star.X
+	}
+	if ident, ok := receiver.(*ast.Ident); ok {
+		return ident.Name + "." + decl.Name.Name
+	}
+	return decl.Name.Name
+}
+
+// reads devolve os argumentos lidos pela função, em ordem alfabética.
+func (a *argumentScanner) reads(function string) ([]string, error) {
+	decl, ok := a.funcs[function]
+	if !ok {
+		return nil, fmt.Errorf("função %s não encontrada", function)
+	}
+	found := map[string]bool{}
+	if err := a.scan(decl, requestParams(decl, -1), found, map[string]bool{}); err != nil {
+		return nil, err
+	}
+
+	reads := make([]string, 0, len(found))
+	for name := range found {
+		reads = append(reads, name)
+	}
+	sort.Strings(reads)
+	return reads, nil
+}
+
+// requestParams devolve os nomes dos parâmetros mcp.CallToolRequest da função;
+// com position >= 0, só o parâmetro nessa posição.
+func requestParams(decl *ast.FuncDecl, position int) map[string]bool {
+	names := map[string]bool{}
+	index := 0
+	for _, field := range decl.Type.Params.List {
+		selector, isRequest := field.Type.(*ast.SelectorExpr)
+		isRequest = isRequest && selector.Sel.Name == "CallToolRequest"
+		count := max(len(field.Names), 1)
+		for i := 0; i < count; i++ {
+			if isRequest && len(field.Names) > 0 && (position < 0 || position == index) {
+				names[field.Names[i].Name] = true
+			}
+			index++
+		}
+	}
+	return names
+}
+
+// scan registra as chamadas request.Get*("nome") e request.Require*("nome"), os
+// acessos request.GetArguments()["nome"] e segue as funções do pacote que
+// recebem a requisição.
+func (a *argumentScanner) scan(decl *ast.FuncDecl, requests map[string]bool, found, visited map[string]bool) error {
+	visitKey := fmt.Sprintf("%s%v", funcKey(decl), requests)
+	if visited[visitKey] || len(requests) == 0 {
+		return nil
+	}
+	visited[visitKey] = true
+
+	isRequest := func(expr ast.Expr) bool {
+		ident, ok := expr.(*ast.Ident)
+		return ok && requests[ident.Name]
+	}
+	requestMethod := func(expr ast.Expr) string {
+		if selector, ok := expr.(*ast.SelectorExpr); ok && isRequest(selector.X) {
+			return selector.Sel.Name
+		}
+		return ""
+	}
+	record := func(expr ast.Expr) {
+		if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			if name, err := strconv.Unquote(lit.Value); err == nil {
+				found[name] = true
+			}
+		}
+	}
+
+	var err error
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		switch n := node.(type) {
+		case *ast.IndexExpr:
+			if call, ok := n.X.(*ast.CallExpr); ok && requestMethod(call.Fun) == "GetArguments" {
+				record(n.Index)
+			}
+		case *ast.CallExpr:
+			method := requestMethod(n.Fun)
+			if (strings.HasPrefix(method, "Get") || strings.HasPrefix(method, "Require")) && len(n.Args) > 0 {
+				record(n.Args[0])
+			}
+			for position, arg := range n.Args {
+				if !isRequest(arg) {
+					continue
+				}
+				callee := a.callee(n.Fun)
+				if callee == nil {
+					err = fmt.Errorf("%s passa a requisição para uma função desconhecida", funcKey(decl))
+					return false
+				}
+				err = a.scan(callee, requestParams(callee, position), found, visited)
+			}
+		}
+		return err == nil
+	})
+	return err
+}
+
+func (a *argumentScanner) callee(fun ast.Expr) *ast.FuncDecl {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return a.funcs[f.Name]
+	case *ast.SelectorExpr:
+		return a.funcs["Server."+f.Sel.Name]
+	}
+	return nil
+}
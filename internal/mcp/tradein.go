@@ -0,0 +1,266 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"mcp-gemini-go/internal/finance"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// conditionFactors ajustam o valor pelo estado geral, tomando 'Bom' como a
+// referência do histórico de valorização.
+var conditionFactors = map[string]float64{
+	"Excelente": 1.08,
+	"Muito Bom": 1.04,
+	"Bom":       1.00,
+	"Regular":   0.90,
+	"Ruim":      0.75,
+}
+
+// Ajuste por quilometragem: 1% a cada 10.000 km de diferença, limitado a 20%.
+const (
+	expectedAnnualKm  = 15000
+	kmAdjustmentStep  = 10000
+	kmAdjustmentRate  = 0.01
+	maxKmAdjustment   = 0.20
+	comparableYearGap = 2
+	maxComparables    = 10
+)
+
+type tradeInComparable struct {
+	Year          int     `json:"ano"`
+	Km            int     `json:"quilometragem"`
+	Condition     string  `json:"estado_geral"`
+	Appraised     float64 `json:"valor_avaliado"`
+	Adjusted      float64 `json:"valor_ajustado"`
+	AppraisalDate string  `json:"data_avaliacao"`
+}
+
+type tradeInAppraisal struct {
+	Brand            string              `json:"marca,omitempty"`
+	Model            string              `json:"modelo"`
+	Year             int                 `json:"ano"`
+	Km               int                 `json:"quilometragem"`
+	Condition        string              `json:"estado_geral"`
+	DepreciationRate float64             `json:"depreciacao_anual"`
+	HistoryValue     *float64            `json:"valor_historico,omitempty"`
+	Comparables      []tradeInComparable `json:"comparaveis"`
+	MarketValue      float64             `json:"valor_mercado"`
+	Minimum          float64             `json:"valor_minimo"`
+	Maximum          float64             `json:"valor_maximo"`
+	Debts            float64             `json:"valor_debitos"`
+	NetValue         float64             `json:"valor_liquido"`
+	Notes            []string            `json:"observacoes,omitempty"`
+}
+
+// kmFactor corrige o valor por excessKm quilômetros acima (positivo) ou abaixo
+// (negativo) da referência.
+func kmFactor(excessKm float64) float64 {
+	adjustment := excessKm / kmAdjustmentStep * kmAdjustmentRate
+	return 1 - math.Min(math.Max(adjustment, -maxKmAdjustment), maxKmAdjustment)
+}
+
+// yearsSince é o tempo em anos, com fração, desde date.
+func yearsSince(date time.Time) float64 {
+	years := time.Since(date).Hours() / 24 / 365
+	return math.Max(years, 0)
+}
+
+// catalogModel é um modelo do catálogo com o nome da marca.
+type catalogModel struct {
+	ID    int
+	Brand string
+	Model string
+}
+
+// findModel busca o modelo no catálogo; devolve nil se não estiver cadastrado.
+func (s *Server) findModel(ctx context.Context, brand, model string) (*catalogModel, error) {
+	query := `
+		SELECT mo.id_modelos, m.marca, mo.modelo
+		FROM modelos mo
+		JOIN marcas m ON mo.id_marcas = m.id_marcas
+		WHERE LOWER(mo.modelo) = LOWER($2)
+		AND ($1 = '' OR LOWER(m.marca) = LOWER($1))
+		LIMIT 1
+	`
+
+	var found catalogModel
+	err := s.DB.QueryRowContext(ctx, query, brand, model).Scan(&found.ID, &found.Brand, &found.Model)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar modelo: %w", err)
+	}
+	return &found, nil
+}
+
+// historyValue projeta para hoje o valor do histórico do ano-modelo mais
+// próximo, corrigido pelos anos de diferença. Sem histórico devolve nil.
+func (s *Server) historyValue(ctx context.Context, modelID, year int, rate float64) (*float64, error) {
+	query := `
+		SELECT ano_modelo, mes_referencia, valor_atual
+		FROM historico_valorizacao
+		WHERE id_modelos = $1 AND valor_atual > 0
+		ORDER BY ABS(ano_modelo - $2) ASC, mes_referencia DESC
+		LIMIT 1
+	`
+
+	var modelYear int
+	var reference time.Time
+	var value float64
+	err := s.DB.QueryRowContext(ctx, query, modelID, year).Scan(&modelYear, &reference, &value)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar histórico de valorização: %w", err)
+	}
+
+	age := yearsSince(reference) + float64(modelYear-year)
+	return floatPtr(value * math.Pow(1-rate, age)), nil
+}
+
+// comparableAppraisals lê as avaliações do mesmo modelo em anos próximos e
+// ajusta cada uma para o ano, a quilometragem e o estado do veículo avaliado.
+func (s *Server) comparableAppraisals(ctx context.Context, brand, model string, year, km int, condition string, rate float64) ([]tradeInComparable, error) {
+	query := `
+		SELECT ano, COALESCE(quilometragem, 0), COALESCE(estado_geral, 'Bom'), valor_avaliado, data_avaliacao
+		FROM avaliacoes_usados
+		WHERE LOWER(modelo) = LOWER($2)
+		AND ($1 = '' OR LOWER(marca) = LOWER($1))
+		AND ano BETWEEN $3::int - $4::int AND $3::int + $4::int
+		AND valor_avaliado > 0
+		ORDER BY data_avaliacao DESC
+		LIMIT $5
+	`
+
+	rows, err := s.DB.QueryContext(ctx, query, brand, model, year, comparableYearGap, maxComparables)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar avaliações de usados: %w", err)
+	}
+	defer rows.Close()
+
+	comparables := []tradeInComparable{}
+	for rows.Next() {
+		var c tradeInComparable
+		var appraisedAt time.Time
+		if err := rows.Scan(&c.Year, &c.Km, &c.Condition, &c.Appraised, &appraisedAt); err != nil {
+			return nil, fmt.Errorf("erro ao ler avaliação de usado: %w", err)
+		}
+		c.AppraisalDate = appraisedAt.Format("2006-01-02")
+
+		value := c.Appraised * math.Pow(1-rate, yearsSince(appraisedAt)+float64(c.Year-year))
+		value *= kmFactor(float64(km - c.Km))
+		if factor, ok := conditionFactors[c.Condition]; ok {
+			value *= conditionFactors[condition] / factor
+		}
+		c.Adjusted = finance.Round(value)
+		comparables = append(comparables, c)
+	}
+	return comparables, rows.Err()
+}
+
+// AppraiseTradeIn estima o valor do usado dado na troca pelo histórico de
+// valorização e pelas avaliações comparáveis, descontando os débitos.
+func (s *Server) AppraiseTradeIn(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	model, err := request.RequireString("model")
+	if err != nil || model == "" {
+		return mcp.NewToolResultError("parâmetro 'model' é obrigatório"), nil
+	}
+	year, err := request.RequireInt("year")
+	if err != nil || year < 1950 || year > time.Now().Year()+1 {
+		return mcp.NewToolResultError("parâmetro 'year' é obrigatório e deve ser um ano-modelo válido"), nil
+	}
+	km, err := request.RequireInt("km")
+	if err != nil || km < 0 {
+		return mcp.NewToolResultError("parâmetro 'km' é obrigatório e não pode ser negativo"), nil
+	}
+	condition := request.GetString("condition", "Bom")
+	if _, ok := conditionFactors[condition]; !ok {
+		return mcp.NewToolResultError("estado geral deve ser Excelente, Muito Bom, Bom, Regular ou Ruim"), nil
+	}
+	debts := request.GetFloat("debts", 0)
+	if debts < 0 {
+		return mcp.NewToolResultError("os débitos não podem ser negativos"), nil
+	}
+	brand := request.GetString("brand", "")
+
+	result := tradeInAppraisal{
+		Brand:     brand,
+		Model:     model,
+		Year:      year,
+		Km:        km,
+		Condition: condition,
+		Debts:     debts,
+	}
+
+	rate := defaultDepreciationRate
+	catalog, err := s.findModel(ctx, brand, model)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if catalog != nil {
+		var found bool
+		if rate, found, err = s.depreciationRate(ctx, catalog.ID); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if !found {
+			result.Notes = append(result.Notes, fmt.Sprintf("sem histórico de valorização; depreciação estimada em %.0f%% ao ano", defaultDepreciationRate*100))
+		}
+		if result.HistoryValue, err = s.historyValue(ctx, catalog.ID, year, rate); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+	result.DepreciationRate = finance.Round(rate * 100)
+
+	var values []float64
+	if result.HistoryValue != nil {
+		age := math.Max(float64(time.Now().Year()-year), 0)
+		value := *result.HistoryValue * kmFactor(float64(km)-age*expectedAnnualKm) * conditionFactors[condition]
+		result.HistoryValue = floatPtr(finance.Round(value))
+		values = append(values, *result.HistoryValue)
+	}
+
+	if result.Comparables, err = s.comparableAppraisals(ctx, brand, model, year, km, condition, rate); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(result.Comparables) == 0 && result.HistoryValue == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("sem histórico de valorização nem avaliações comparáveis para %s %d", model, year)), nil
+	}
+
+	// O histórico e a média das avaliações comparáveis têm o mesmo peso.
+	var comparableMean float64
+	for _, c := range result.Comparables {
+		comparableMean += c.Adjusted / float64(len(result.Comparables))
+		values = append(values, c.Adjusted)
+	}
+	switch {
+	case result.HistoryValue == nil:
+		result.MarketValue = finance.Round(comparableMean)
+	case len(result.Comparables) == 0:
+		result.MarketValue = *result.HistoryValue
+	default:
+		result.MarketValue = finance.Round((*result.HistoryValue + comparableMean) / 2)
+	}
+
+	result.Minimum, result.Maximum = values[0], values[0]
+	for _, value := range values {
+		result.Minimum = math.Min(result.Minimum, value)
+		result.Maximum = math.Max(result.Maximum, value)
+	}
+
+	result.NetValue = finance.Round(math.Max(result.MarketValue-debts, 0))
+	if debts > result.MarketValue {
+		result.Notes = append(result.Notes, fmt.Sprintf("os débitos superam o valor do veículo em R$ %.2f", debts-result.MarketValue))
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
@@ -0,0 +1,35 @@
+package mcp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestKmFactor(t *testing.T) {
+	tests := []struct {
+		excessKm float64
+		want     float64
+	}{
+		{0, 1},
+		{10000, 0.99},
+		{-10000, 1.01},
+		{55000, 0.945},
+		{200000, 1 - maxKmAdjustment},
+		{-200000, 1 + maxKmAdjustment},
+	}
+	for _, tt := range tests {
+		if got := kmFactor(tt.excessKm); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("kmFactor(%.0f) = %v, esperado %v", tt.excessKm, got, tt.want)
+		}
+	}
+}
+
+func TestYearsSince(t *testing.T) {
+	if years := yearsSince(time.Now().AddDate(0, 0, -365)); math.Abs(years-1) > 0.01 {
+		t.Errorf("yearsSince(um ano atrás) = %v, esperado 1", years)
+	}
+	if years := yearsSince(time.Now().AddDate(1, 0, 0)); years != 0 {
+		t.Errorf("data futura deveria dar zero, veio %v", years)
+	}
+}
@@ -0,0 +1,193 @@
+package mcp
+
+import "mcp-gemini-go/internal/finance"
+
+// Os tipos abaixo são o formato JSON devolvido pelas ferramentas. O servidor
+// serializa com eles e os clientes decodificam com CallToolJSON.
+
+type Vehicle struct {
+	ID                 int     `json:"id_veiculo"`
+	Brand              string  `json:"marca"`
+	Model              string  `json:"modelo"`
+	Version            string  `json:"versao"`
+	Price              float64 `json:"preco_venda"`
+	VehicleType        string  `json:"tipo_veiculo"`
+	Status             string  `json:"status_veiculo"`
+	UrbanConsumption   float64 `json:"consumo_urbano"`
+	HighwayConsumption float64 `json:"consumo_rodoviario"`
+	Horsepower         int     `json:"potencia_cv"`
+	AnnualIPVA         float64 `json:"ipva_anual"`
+	ModelYear          int     `json:"ano_modelo"`
+	Color              string  `json:"cor"`
+	FuelType           string  `json:"tipo_combustivel"`
+	// PromotionalPrice e Campaign vêm da campanha de maior desconto vigente
+	// para o modelo.
+	PromotionalPrice float64 `json:"preco_promocional,omitempty"`
+	Campaign         string  `json:"campanha,omitempty"`
+	// RecallAlert é preenchido quando o ano-modelo tem recall de gravidade
+	// Critica ou Alta ainda não resolvido.
+	RecallAlert string `json:"alerta_recall,omitempty"`
+	// AdjustedIPVA é o IPVA com o melhor incentivo fiscal vigente sem
+	// restrição de cidade, preenchido só quando é menor que AnnualIPVA.
+	AdjustedIPVA *float64 `json:"ipva_com_incentivo,omitempty"`
+}
+
+type VehiclePriceStats struct {
+	Count    int     `json:"quantidade"`
+	MinPrice float64 `json:"preco_minimo"`
+	AvgPrice float64 `json:"preco_medio"`
+	MaxPrice float64 `json:"preco_maximo"`
+}
+
+type FinancingOption struct {
+	Bank         string  `json:"banco"`
+	Type         string  `json:"tipo"`
+	MonthlyRate  float64 `json:"taxa_mes"`
+	AnnualRate   float64 `json:"taxa_ano"`
+	Installments int     `json:"parcelas"`
+	DownPayment  float64 `json:"valor_entrada"`
+	Installment  float64 `json:"valor_parcela"`
+	Total        float64 `json:"valor_total"`
+	Notes        string  `json:"observacoes"`
+}
+
+type FinancingSimulation struct {
+	VehiclePrice     float64               `json:"valor_veiculo"`
+	DownPayment      float64               `json:"valor_entrada"`
+	TradeIn          float64               `json:"valor_troca,omitempty"`
+	FinancedAmount   float64               `json:"valor_financiado"`
+	Installments     int                   `json:"numero_parcelas"`
+	System           finance.System        `json:"sistema"`
+	Installment      float64               `json:"valor_parcela"`
+	FirstInstallment float64               `json:"valor_primeira_parcela"`
+	LastInstallment  float64               `json:"valor_ultima_parcela"`
+	Total            float64               `json:"valor_total"`
+	TotalInterest    float64               `json:"total_juros"`
+	AnnualRate       float64               `json:"taxa_juros_ano"`
+	MonthlyRate      float64               `json:"taxa_juros_mes"`
+	Bank             string                `json:"banco_financiadora"`
+	Campaign         *Campaign             `json:"campanha,omitempty"`
+	Schedule         []finance.Installment `json:"tabela_amortizacao,omitempty"`
+}
+
+// CETSimulation é a simulação acrescida dos custos que compõem o CET. As taxas
+// de CET são devolvidas em porcentagem, como as demais.
+type CETSimulation struct {
+	FinancingSimulation
+	Costs      finance.CET `json:"custos"`
+	CETMonthly float64     `json:"cet_mes"`
+	CETAnnual  float64     `json:"cet_ano"`
+}
+
+// FinancingSolution é a simulação completa devolvida por solve_financing, com o
+// nome do argumento que foi calculado.
+type FinancingSolution struct {
+	Solved string `json:"resolvido"`
+	FinancingSimulation
+	Note string `json:"observacao,omitempty"`
+}
+
+// Campaign é uma campanha vigente de campanhas_promocoes. A taxa especial é em
+// porcentagem ao mês e fica ausente quando a campanha é só de desconto.
+type Campaign struct {
+	Name            string   `json:"nome_campanha"`
+	Brand           string   `json:"marca"`
+	Model           string   `json:"modelo"`
+	Description     string   `json:"descricao"`
+	DiscountPercent float64  `json:"desconto_percentual"`
+	DiscountValue   float64  `json:"desconto_valor"`
+	SpecialRate     *float64 `json:"taxa_juros_especial,omitempty"`
+	StartDate       string   `json:"data_inicio"`
+	EndDate         string   `json:"data_fim"`
+}
+
+// VehicleComparison é o resultado de compare_vehicles. Os valores de cada linha
+// seguem a ordem de Vehicles; nulo indica dado não cadastrado.
+type VehicleComparison struct {
+	Vehicles []ComparedVehicle `json:"veiculos"`
+	Rows     []ComparisonRow   `json:"comparacao"`
+}
+
+// ComparedVehicle é o veículo comparado com o número de dimensões que venceu.
+type ComparedVehicle struct {
+	Vehicle
+	Wins int `json:"vitorias"`
+}
+
+// ComparisonRow é uma dimensão da comparação. Better é "maior" ou "menor" e
+// WinnerID só é preenchido quando um único veículo tem o melhor valor.
+type ComparisonRow struct {
+	Dimension string     `json:"dimensao"`
+	Unit      string     `json:"unidade,omitempty"`
+	Better    string     `json:"melhor"`
+	Values    []*float64 `json:"valores"`
+	WinnerID  int        `json:"vencedor_id,omitempty"`
+}
+
+// Recall é um recall ou problema conhecido de recalls_problemas. Os anos
+// ausentes indicam que a faixa de ano-modelo não tem limite.
+type Recall struct {
+	Number         string  `json:"numero_recall,omitempty"`
+	Type           string  `json:"tipo_problema"`
+	Category       string  `json:"categoria,omitempty"`
+	Description    string  `json:"descricao"`
+	Severity       string  `json:"gravidade"`
+	Solution       string  `json:"solucao,omitempty"`
+	Status         string  `json:"status_solucao"`
+	RepairCost     float64 `json:"custo_reparo_estimado,omitempty"`
+	ModelYearStart *int    `json:"ano_modelo_inicio,omitempty"`
+	ModelYearEnd   *int    `json:"ano_modelo_fim,omitempty"`
+	AnnouncedAt    string  `json:"data_comunicado,omitempty"`
+}
+
+// RecallReport é o resultado de check_recalls. Year zero indica todos os
+// anos-modelo.
+type RecallReport struct {
+	Brand    string   `json:"marca"`
+	Model    string   `json:"modelo"`
+	Year     int      `json:"ano_modelo,omitempty"`
+	Alert    string   `json:"alerta,omitempty"`
+	Open     []Recall `json:"abertos"`
+	Resolved []Recall `json:"resolvidos"`
+}
+
+// TaxIncentive é um incentivo fiscal vigente de incentivos_fiscais. City fica
+// vazia quando o incentivo não é restrito a uma cidade.
+type TaxIncentive struct {
+	Type            string  `json:"tipo_incentivo"`
+	Description     string  `json:"descricao"`
+	DiscountPercent float64 `json:"desconto_percentual,omitempty"`
+	DiscountValue   float64 `json:"desconto_valor,omitempty"`
+	City            string  `json:"cidade,omitempty"`
+	StartDate       string  `json:"vigencia_inicio,omitempty"`
+	EndDate         string  `json:"vigencia_fim,omitempty"`
+	Conditions      string  `json:"condicoes,omitempty"`
+	Agency          string  `json:"orgao_responsavel,omitempty"`
+	Law             string  `json:"lei_decreto,omitempty"`
+}
+
+// VehicleSearch é uma página do resultado de search_vehicles. Total conta
+// todos os veículos que atendem aos filtros, não só os da página.
+type VehicleSearch struct {
+	Total    int               `json:"total"`
+	Page     int               `json:"pagina"`
+	PageSize int               `json:"itens_por_pagina"`
+	Pages    int               `json:"total_paginas"`
+	Vehicles []SearchedVehicle `json:"veiculos"`
+}
+
+// SearchedVehicle é o veículo encontrado com os itens pedidos na busca,
+// separados entre de série e opcionais.
+type SearchedVehicle struct {
+	Vehicle
+	Category      string         `json:"categoria"`
+	Km            int            `json:"quilometragem"`
+	NCAP          *float64       `json:"nota_latin_ncap,omitempty"`
+	StandardItems []string       `json:"itens_serie,omitempty"`
+	OptionalItems []OptionalItem `json:"itens_opcionais,omitempty"`
+}
+
+type OptionalItem struct {
+	Item  string  `json:"item"`
+	Price float64 `json:"preco_adicional"`
+}
@@ -5,33 +5,43 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"math"
 	"net/http"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 
+	"mcp-gemini-go/internal/conversation"
+	"mcp-gemini-go/internal/finance"
+	"mcp-gemini-go/internal/llm"
 	"mcp-gemini-go/internal/mcp"
+	"mcp-gemini-go/internal/web/services"
 )
 
 type ChatHandler struct {
-	mcpClient *mcp.Client
-	tools     []map[string]interface{}
+	mcpClient    *mcp.Client
+	tools        []map[string]interface{}
+	orchestrator *services.ChatOrchestrator
 }
 
 type ChatRequest struct {
-	Message string `json:"message"`
+	Message   string `json:"message"`
+	SessionID string `json:"session_id,omitempty"`
 }
 
 type ChatResponse struct {
-	Response string `json:"response"`
-	Error    string `json:"error,omitempty"`
+	Response  string `json:"response"`
+	Source    string `json:"source,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	Error     string `json:"error,omitempty"`
 }
 
-func NewChatHandler(mcpClient *mcp.Client, tools []map[string]interface{}) *ChatHandler {
+func NewChatHandler(mcpClient *mcp.Client, tools []map[string]interface{}, orchestrator *services.ChatOrchestrator) *ChatHandler {
 	return &ChatHandler{
-		mcpClient: mcpClient,
-		tools:     tools,
+		mcpClient:    mcpClient,
+		tools:        tools,
+		orchestrator: orchestrator,
 	}
 }
 
@@ -59,14 +69,85 @@ func (h *ChatHandler) HandleChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
-	responseText := h.processQuestionWithDatabase(ctx, req.Message)
+	session := sessionID(w, r)
+	conversationID, status, message := h.conversation(r, session, req.SessionID)
+	if status != http.StatusOK {
+		writeJSON(w, status, ChatResponse{Error: message})
+		return
+	}
+	answer := h.orchestrator.Answer(r.Context(), session, conversationID, req.Message, h.processQuestionWithDatabase)
 
-	response := ChatResponse{Response: responseText}
+	response := ChatResponse{Response: answer.Content, Source: answer.Source, SessionID: conversationID}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// HandleChatStream responde como HandleChat, mas envia a resposta em Server-Sent
+// Events: "token" para cada trecho de texto, "tool_start" e "tool_end" para as
+// chamadas de ferramenta, "reset" quando o texto parcial deve ser descartado e
+// "done" ao final.
+func (h *ChatHandler) HandleChatStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Formato de requisição inválido")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "Streaming não suportado")
+		return
+	}
+
+	session := sessionID(w, r)
+	conversationID, status, message := h.conversation(r, session, req.SessionID)
+	if status != http.StatusOK {
+		writeJSONError(w, status, message)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, data interface{}) {
+		if r.Context().Err() != nil {
+			return
+		}
+		payload, _ := json.Marshal(data)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		flusher.Flush()
+	}
+
+	answer := h.orchestrator.Stream(r.Context(), session, conversationID, req.Message, h.processQuestionWithDatabase, func(event llm.StreamEvent) {
+		if event.Type == llm.EventToolStart {
+			event.Text = fmt.Sprintf("consultando %s…", event.Tool)
+		}
+		send(event.Type, event)
+	})
+
+	send("done", ChatResponse{Response: answer.Content, Source: answer.Source, SessionID: conversationID})
+}
+
+// conversation resolve a conversa da requisição e confere se ela pode ser usada
+// pela sessão; em caso de erro devolve o status e a mensagem para o cliente.
+func (h *ChatHandler) conversation(r *http.Request, session, requested string) (string, int, string) {
+	id, ok := conversationID(session, requested)
+	if !ok {
+		return "", http.StatusBadRequest, fmt.Sprintf("session_id inválido: use até %d letras, dígitos, '-' ou '_'", conversation.MaxIDLength)
+	}
+	if !h.orchestrator.CanUse(r.Context(), session, id) {
+		return "", http.StatusNotFound, "Conversa não encontrada"
+	}
+	return id, http.StatusOK, ""
+}
+
 func (h *ChatHandler) processQuestionWithDatabase(ctx context.Context, message string) string {
 	if result := h.handleSpecificQuestions(ctx, message); result != "" {
 		return result
@@ -77,6 +158,7 @@ func (h *ChatHandler) processQuestionWithDatabase(ctx context.Context, message s
 • "carro barato" - veículos até R$ 100.000
 • "carro mais caro" - veículos premium  
 • "simular [modelo] com entrada de R$ [valor] em [parcelas]x"
+• "comparar [modelo] e [modelo]" - tabela lado a lado
 • "financiamento" - melhores taxas disponíveis
 
 📊 **Todas as informações são baseadas em dados reais da nossa concessionária:**
@@ -106,6 +188,12 @@ func (h *ChatHandler) handleSpecificQuestions(ctx context.Context, message strin
 		})
 	}
 
+	if strings.Contains(messageToLower, "compar") {
+		if response := h.handleComparisonQuestion(ctx, messageToLower); response != "" {
+			return response
+		}
+	}
+
 	if strings.Contains(messageToLower, "simular") {
 		if strings.Contains(messageToLower, "fiat argo") && strings.Contains(messageToLower, "entrada") {
 			var downPayment float64 = 0.0
@@ -115,15 +203,17 @@ func (h *ChatHandler) handleSpecificQuestions(ctx context.Context, message strin
 
 			installments = h.extractInstallments(messageToLower)
 
-			vehiclePrice := h.getVehiclePrice(ctx, "Fiat", "Argo")
-			if vehiclePrice == 0 {
+			vehicle := h.getVehicle(ctx, "Fiat", "Argo")
+			if vehicle == nil {
 				return "❌ Veículo Fiat Argo não encontrado em nosso estoque. Consulte nossa base com 'carro barato' para ver opções disponíveis."
 			}
 
 			return h.executeCalculateFinancing(ctx, map[string]interface{}{
-				"vehicle_price": vehiclePrice,
+				"vehicle_price": vehicle.Price,
 				"down_payment":  downPayment,
 				"installments":  installments,
+				"system":        h.extractAmortizationSystem(messageToLower),
+				"model":         vehicle.Model,
 			})
 		}
 
@@ -137,6 +227,7 @@ func (h *ChatHandler) handleSpecificQuestions(ctx context.Context, message strin
 				"vehicle_price": avgPrice,
 				"down_payment":  0.0,
 				"installments":  60.0,
+				"system":        h.extractAmortizationSystem(messageToLower),
 			})
 		}
 	}
@@ -154,77 +245,39 @@ func (h *ChatHandler) handleSpecificQuestions(ctx context.Context, message strin
 }
 
 func (h *ChatHandler) executeGetVehiclesAvailable(ctx context.Context, params map[string]interface{}) string {
-	if h.mcpClient == nil || h.mcpClient.Server == nil || h.mcpClient.Server.DB == nil {
+	if h.mcpClient == nil {
 		return "❌ Conexão com base de dados indisponível"
 	}
 
-	query := `
-		SELECT 
-			m.marca,
-			mo.modelo,
-			v.versao,
-			v.preco_venda,
-			v.consumo_urbano,
-			v.consumo_rodoviario,
-			v.potencia_cv,
-			v.ipva_anual,
-			v.ano_modelo,
-			v.cor,
-			v.tipo_combustivel
-		FROM veiculos v
-		JOIN modelos mo ON v.id_modelos = mo.id_modelos  
-		JOIN marcas m ON mo.id_marcas = m.id_marcas
-		WHERE v.status_veiculo = 'Disponivel'
-	`
-
-	var queryArgs []interface{}
-	argIndex := 1
-
-	if maxPrice, ok := params["max_price"]; ok {
-		query += fmt.Sprintf(" AND v.preco_venda <= $%d", argIndex)
-		queryArgs = append(queryArgs, maxPrice)
-		argIndex++
-	}
-
-	if sort, ok := params["sort"]; ok && sort == "expensive" {
-		query += " ORDER BY v.preco_venda DESC"
-	} else {
-		query += " ORDER BY v.preco_venda ASC"
-	}
-
-	query += " LIMIT 3"
-
-	rows, err := h.mcpClient.Server.DB.QueryContext(ctx, query, queryArgs...)
-	if err != nil {
+	var vehicles []mcp.Vehicle
+	if err := h.mcpClient.CallToolJSON(ctx, "get_vehicles_available", params, &vehicles); err != nil {
 		return fmt.Sprintf("❌ Erro ao buscar veículos: %v", err)
 	}
-	defer rows.Close()
 
 	var response strings.Builder
 	response.WriteString("💡 **Baseado em nossa base de dados:**\n\n")
 
-	count := 0
-	for rows.Next() && count < 3 {
-		var marca, modelo, versao, cor, tipoCombustivel string
-		var preco, consumoUrbano, consumoRodoviario, ipva float64
-		var potencia, ano int
-
-		err := rows.Scan(&marca, &modelo, &versao, &preco, &consumoUrbano, &consumoRodoviario, &potencia, &ipva, &ano, &cor, &tipoCombustivel)
-		if err != nil {
-			continue
+	for _, v := range vehicles {
+		response.WriteString(fmt.Sprintf("🚘 **%s %s %s (%s)**\n", v.Brand, v.Model, v.Version, v.Color))
+		response.WriteString(fmt.Sprintf("💰 Preço: R$ %.2f\n", v.Price))
+		if v.PromotionalPrice > 0 {
+			response.WriteString(fmt.Sprintf("🏷️ Preço promocional: R$ %.2f (%s)\n", v.PromotionalPrice, v.Campaign))
 		}
-
-		response.WriteString(fmt.Sprintf("🚘 **%s %s %s (%s)**\n", marca, modelo, versao, cor))
-		response.WriteString(fmt.Sprintf("💰 Preço: R$ %.2f\n", preco))
-		response.WriteString(fmt.Sprintf("📅 Ano: %d\n", ano))
-		response.WriteString(fmt.Sprintf("⚡ Potência: %d cv\n", potencia))
-		response.WriteString(fmt.Sprintf("⛽ Consumo: %.1f (cidade) / %.1f (estrada) km/l\n", consumoUrbano, consumoRodoviario))
-		response.WriteString(fmt.Sprintf("🏛️ IPVA anual: R$ %.2f\n", ipva))
-		response.WriteString(fmt.Sprintf("⛽ Combustível: %s\n\n", tipoCombustivel))
-		count++
+		response.WriteString(fmt.Sprintf("📅 Ano: %d\n", v.ModelYear))
+		response.WriteString(fmt.Sprintf("⚡ Potência: %d cv\n", v.Horsepower))
+		response.WriteString(fmt.Sprintf("⛽ Consumo: %.1f (cidade) / %.1f (estrada) km/l\n", v.UrbanConsumption, v.HighwayConsumption))
+		response.WriteString(fmt.Sprintf("🏛️ IPVA anual: R$ %.2f\n", v.AnnualIPVA))
+		if v.AdjustedIPVA != nil {
+			response.WriteString(fmt.Sprintf("🌱 IPVA com incentivo fiscal: R$ %.2f\n", *v.AdjustedIPVA))
+		}
+		response.WriteString(fmt.Sprintf("⛽ Combustível: %s\n", v.FuelType))
+		if v.RecallAlert != "" {
+			response.WriteString(fmt.Sprintf("🚨 Atenção: %s\n", v.RecallAlert))
+		}
+		response.WriteString("\n")
 	}
 
-	if count == 0 {
+	if len(vehicles) == 0 {
 		response.WriteString("❌ Nenhum veículo encontrado com os critérios especificados.\n")
 	} else {
 		response.WriteString("❓ Gostaria de simular o financiamento para algum desses veículos? Informe o prazo desejado!")
@@ -234,267 +287,156 @@ func (h *ChatHandler) executeGetVehiclesAvailable(ctx context.Context, params ma
 }
 
 func (h *ChatHandler) executeGetBestFinancing(ctx context.Context, params map[string]interface{}) string {
-	if h.mcpClient == nil || h.mcpClient.Server == nil || h.mcpClient.Server.DB == nil {
+	if h.mcpClient == nil {
 		return "❌ Conexão com base de dados indisponível"
 	}
 
-	query := `
-		SELECT 
-			banco_financiadora,
-			tipo_financiamento,
-			taxa_juros_mes,
-			taxa_juros_ano,
-			numero_parcelas,
-			valor_entrada,
-			valor_parcela,
-			valor_total,
-			observacoes
-		FROM financiamentos
-		WHERE aprovado = true
-		ORDER BY taxa_juros_mes ASC LIMIT 3
-	`
-
-	rows, err := h.mcpClient.Server.DB.QueryContext(ctx, query)
-	if err != nil {
+	var financings []mcp.FinancingOption
+	if err := h.mcpClient.CallToolJSON(ctx, "get_best_financing", params, &financings); err != nil {
 		return fmt.Sprintf("❌ Erro ao buscar financiamentos: %v", err)
 	}
-	defer rows.Close()
 
 	var response strings.Builder
 	response.WriteString("💡 **Melhores opções de financiamento:**\n\n")
 
-	count := 0
-	for rows.Next() && count < 3 {
-		var banco, tipo, observacoes string
-		var taxaMes, taxaAno, valorEntrada, valorParcela, valorTotal float64
-		var numeroParcelas int
-
-		err := rows.Scan(&banco, &tipo, &taxaMes, &taxaAno, &numeroParcelas, &valorEntrada, &valorParcela, &valorTotal, &observacoes)
-		if err != nil {
-			continue
-		}
-
-		response.WriteString(fmt.Sprintf("🏦 **%s - %s**\n", banco, tipo))
-		response.WriteString(fmt.Sprintf("💸 Taxa: %.2f%% ao mês / %.2f%% ao ano\n", taxaMes, taxaAno))
-		response.WriteString(fmt.Sprintf("📅 Parcelas: %d\n", numeroParcelas))
-		response.WriteString(fmt.Sprintf("💰 Valor parcela: R$ %.2f\n", valorParcela))
-		response.WriteString(fmt.Sprintf("💵 Valor total: R$ %.2f\n", valorTotal))
-		if observacoes != "" {
-			response.WriteString(fmt.Sprintf("📝 %s\n", observacoes))
+	for _, f := range financings {
+		response.WriteString(fmt.Sprintf("🏦 **%s - %s**\n", f.Bank, f.Type))
+		response.WriteString(fmt.Sprintf("💸 Taxa: %.2f%% ao mês / %.2f%% ao ano\n", f.MonthlyRate, f.AnnualRate))
+		response.WriteString(fmt.Sprintf("📅 Parcelas: %d\n", f.Installments))
+		response.WriteString(fmt.Sprintf("💰 Valor parcela: R$ %.2f\n", f.Installment))
+		response.WriteString(fmt.Sprintf("💵 Valor total: R$ %.2f\n", f.Total))
+		if f.Notes != "" {
+			response.WriteString(fmt.Sprintf("📝 %s\n", f.Notes))
 		}
 		response.WriteString("\n")
-		count++
 	}
 
-	if count == 0 {
+	if len(financings) == 0 {
 		response.WriteString("❌ Nenhuma opção de financiamento encontrada.\n")
 	}
 
 	return response.String()
 }
 
+// executeCalculateFinancing repassa params para calculate_financing; sem
+// vehicle_price, simula sobre o preço médio do estoque.
 func (h *ChatHandler) executeCalculateFinancing(ctx context.Context, params map[string]interface{}) string {
-	if h.mcpClient == nil || h.mcpClient.Server == nil || h.mcpClient.Server.DB == nil {
+	if h.mcpClient == nil {
 		return "❌ Conexão com base de dados indisponível"
 	}
 
-	vehiclePrice := 0.0
-	if vp, ok := params["vehicle_price"]; ok {
-		vehiclePrice = vp.(float64)
-	}
-
-	if vehiclePrice == 0 {
-		vehiclePrice = h.getAverageVehiclePrice(ctx)
-		if vehiclePrice == 0 {
+	if price, _ := params["vehicle_price"].(float64); price == 0 {
+		avgPrice := h.getAverageVehiclePrice(ctx)
+		if avgPrice == 0 {
 			return "❌ Não foi possível obter informações de preço da base de dados"
 		}
+		params["vehicle_price"] = avgPrice
 	}
-
-	downPayment := 0.0
-	if dp, ok := params["down_payment"]; ok {
-		downPayment = dp.(float64)
+	if _, ok := params["installments"]; !ok {
+		params["installments"] = 60.0
 	}
 
-	installments := 60.0
-	if inst, ok := params["installments"]; ok {
-		installments = inst.(float64)
+	var simulation mcp.FinancingSimulation
+	if err := h.mcpClient.CallToolJSON(ctx, "calculate_financing", params, &simulation); err != nil {
+		return fmt.Sprintf("❌ Erro ao simular financiamento: %v", err)
 	}
 
-	vehicleInfo := h.getVehicleByPrice(ctx, vehiclePrice)
-
-	query := `
-		SELECT taxa_juros_ano, banco_financiadora
-		FROM financiamentos 
-		WHERE aprovado = true 
-		ORDER BY taxa_juros_ano ASC 
-		LIMIT 1
-	`
-
-	row := h.mcpClient.Server.DB.QueryRowContext(ctx, query)
-	var interestRate float64
-	var bank string
-
-	if err := row.Scan(&interestRate, &bank); err != nil {
-		return "❌ Nenhuma opção de financiamento encontrada na base de dados"
-	}
-
-	interestRate = interestRate / 100
-
-	financeAmount := vehiclePrice - downPayment
-	monthlyRate := interestRate / 12
-
-	var monthlyPayment float64
-	if monthlyRate > 0 {
-		power := 1.0
-		for i := 0; i < int(installments); i++ {
-			power *= (1 + monthlyRate)
-		}
-		monthlyPayment = financeAmount * (monthlyRate * power) / (power - 1)
-	} else {
-		monthlyPayment = financeAmount / installments
-	}
-
-	totalAmount := monthlyPayment * installments
+	vehicle := h.getVehicleByPrice(ctx, simulation.VehiclePrice)
 
 	var response strings.Builder
 	response.WriteString("💡 **Simulação de financiamento baseada em nossa base de dados:**\n\n")
 
-	if vehicleInfo != nil {
+	if vehicle != nil {
 		response.WriteString("🚘 **Veículo Selecionado:**\n")
-		if marca, ok := vehicleInfo["marca"].(string); ok {
-			response.WriteString(fmt.Sprintf("Marca: %s\n", marca))
-		}
-		if modelo, ok := vehicleInfo["modelo"].(string); ok {
-			response.WriteString(fmt.Sprintf("Modelo: %s\n", modelo))
-		}
-		if versao, ok := vehicleInfo["versao"].(string); ok {
-			response.WriteString(fmt.Sprintf("Versão: %s\n", versao))
-		}
-		if cor, ok := vehicleInfo["cor"].(string); ok {
-			response.WriteString(fmt.Sprintf("Cor: %s\n", cor))
-		}
-		if consumoUrbano, ok := vehicleInfo["consumo_urbano"].(float64); ok {
-			if consumoRodoviario, ok2 := vehicleInfo["consumo_rodoviario"].(float64); ok2 {
-				response.WriteString(fmt.Sprintf("Consumo: %.1f (cidade) / %.1f (estrada) km/l\n", consumoUrbano, consumoRodoviario))
-			}
-		}
-		if potencia, ok := vehicleInfo["potencia_cv"].(int); ok {
-			response.WriteString(fmt.Sprintf("Potência: %d cv\n", potencia))
-		}
-		if ipva, ok := vehicleInfo["ipva_anual"].(float64); ok {
-			response.WriteString(fmt.Sprintf("IPVA anual: R$ %.2f\n", ipva))
-		}
+		response.WriteString(fmt.Sprintf("Marca: %s\n", vehicle.Brand))
+		response.WriteString(fmt.Sprintf("Modelo: %s\n", vehicle.Model))
+		response.WriteString(fmt.Sprintf("Versão: %s\n", vehicle.Version))
+		response.WriteString(fmt.Sprintf("Cor: %s\n", vehicle.Color))
+		response.WriteString(fmt.Sprintf("Consumo: %.1f (cidade) / %.1f (estrada) km/l\n", vehicle.UrbanConsumption, vehicle.HighwayConsumption))
+		response.WriteString(fmt.Sprintf("Potência: %d cv\n", vehicle.Horsepower))
+		response.WriteString(fmt.Sprintf("IPVA anual: R$ %.2f\n", vehicle.AnnualIPVA))
 		response.WriteString("\n")
 	}
 
+	bankLabel := "Banco com melhor taxa"
+	if bank, _ := params["bank"].(string); bank != "" {
+		bankLabel = "Banco"
+	}
+
 	response.WriteString("💰 **Detalhes do Financiamento:**\n")
-	response.WriteString(fmt.Sprintf("🚘 Valor do veículo: R$ %.2f\n", vehiclePrice))
-	response.WriteString(fmt.Sprintf("💰 Entrada: R$ %.2f\n", downPayment))
-	response.WriteString(fmt.Sprintf("💵 Valor financiado: R$ %.2f\n", financeAmount))
-	response.WriteString(fmt.Sprintf("🏦 **Banco com melhor taxa: %s**\n", bank))
-	response.WriteString(fmt.Sprintf("📊 Taxa de juros: %.2f%% ao ano (%.2f%% ao mês)\n", interestRate*100, monthlyRate*100))
-	response.WriteString(fmt.Sprintf("📅 Número de parcelas: %.0f\n", installments))
-	response.WriteString(fmt.Sprintf("💸 Valor da parcela: R$ %.2f\n", monthlyPayment))
-	response.WriteString(fmt.Sprintf("💵 Valor total a pagar: R$ %.2f\n", totalAmount))
-	response.WriteString(fmt.Sprintf("💲 Total de juros: R$ %.2f\n", totalAmount-financeAmount))
+	response.WriteString(fmt.Sprintf("🚘 Valor do veículo: R$ %.2f\n", simulation.VehiclePrice))
+	response.WriteString(fmt.Sprintf("💰 Entrada: R$ %.2f\n", simulation.DownPayment))
+	response.WriteString(fmt.Sprintf("💵 Valor financiado: R$ %.2f\n", simulation.FinancedAmount))
+	response.WriteString(fmt.Sprintf("🏦 **%s: %s**\n", bankLabel, simulation.Bank))
+	response.WriteString(fmt.Sprintf("📊 Taxa de juros: %.2f%% ao ano (%.2f%% ao mês)\n", simulation.AnnualRate, simulation.MonthlyRate))
+	if simulation.Campaign != nil {
+		response.WriteString(fmt.Sprintf("🏷️ Taxa especial da campanha %s (válida até %s): %s\n",
+			simulation.Campaign.Name, simulation.Campaign.EndDate, simulation.Campaign.Description))
+	}
+	response.WriteString(fmt.Sprintf("📅 Número de parcelas: %d\n", simulation.Installments))
+	if simulation.System == finance.SAC {
+		response.WriteString("📉 Sistema: SAC (parcelas decrescentes)\n")
+		response.WriteString(fmt.Sprintf("💸 Primeira parcela: R$ %.2f\n", simulation.FirstInstallment))
+		response.WriteString(fmt.Sprintf("💸 Última parcela: R$ %.2f\n", simulation.LastInstallment))
+	} else {
+		response.WriteString(fmt.Sprintf("💸 Valor da parcela: R$ %.2f\n", simulation.Installment))
+	}
+	response.WriteString(fmt.Sprintf("💵 Valor total a pagar: R$ %.2f\n", simulation.Total))
+	response.WriteString(fmt.Sprintf("💲 Total de juros: R$ %.2f\n", simulation.TotalInterest))
 
 	return response.String()
 }
 
-func (h *ChatHandler) getVehiclePrice(ctx context.Context, marca, modelo string) float64 {
-	if h.mcpClient == nil || h.mcpClient.Server == nil || h.mcpClient.Server.DB == nil {
-		return 0
+func (h *ChatHandler) getVehicle(ctx context.Context, marca, modelo string) *mcp.Vehicle {
+	if h.mcpClient == nil {
+		return nil
 	}
 
-	query := `
-		SELECT v.preco_venda
-		FROM veiculos v
-		JOIN modelos mo ON v.id_modelos = mo.id_modelos  
-		JOIN marcas m ON mo.id_marcas = m.id_marcas
-		WHERE LOWER(m.marca) = LOWER($1) AND LOWER(mo.modelo) = LOWER($2)
-		AND v.status_veiculo = 'Disponivel'
-		LIMIT 1
-	`
-
-	var price float64
-	row := h.mcpClient.Server.DB.QueryRowContext(ctx, query, marca, modelo)
-	if err := row.Scan(&price); err != nil {
-		return 0
+	var vehicles []mcp.Vehicle
+	err := h.mcpClient.CallToolJSON(ctx, "get_vehicles_available", map[string]interface{}{
+		"brand": marca,
+		"model": modelo,
+		"sort":  "cheap",
+	}, &vehicles)
+	if err != nil || len(vehicles) == 0 {
+		return nil
 	}
 
-	return price
+	return &vehicles[0]
 }
 
 func (h *ChatHandler) getAverageVehiclePrice(ctx context.Context) float64 {
-	if h.mcpClient == nil || h.mcpClient.Server == nil || h.mcpClient.Server.DB == nil {
+	if h.mcpClient == nil {
 		return 0
 	}
 
-	query := `
-		SELECT AVG(v.preco_venda)
-		FROM veiculos v
-		WHERE v.status_veiculo = 'Disponivel'
-	`
-
-	var avgPrice float64
-	row := h.mcpClient.Server.DB.QueryRowContext(ctx, query)
-	if err := row.Scan(&avgPrice); err != nil {
+	var stats mcp.VehiclePriceStats
+	if err := h.mcpClient.CallToolJSON(ctx, "get_vehicle_price_stats", nil, &stats); err != nil {
 		return 0
 	}
 
-	return avgPrice
+	return stats.AvgPrice
 }
 
-func (h *ChatHandler) getVehicleByPrice(ctx context.Context, targetPrice float64) map[string]interface{} {
-	if h.mcpClient == nil || h.mcpClient.Server == nil || h.mcpClient.Server.DB == nil {
+// getVehicleByPrice devolve o veículo disponível mais próximo de targetPrice,
+// desde que a diferença não passe de R$ 5.000.
+func (h *ChatHandler) getVehicleByPrice(ctx context.Context, targetPrice float64) *mcp.Vehicle {
+	if h.mcpClient == nil {
 		return nil
 	}
 
-	query := `
-		SELECT 
-			m.marca,
-			mo.modelo,
-			v.versao,
-			v.preco_venda,
-			v.consumo_urbano,
-			v.consumo_rodoviario,
-			v.potencia_cv,
-			v.ipva_anual,
-			v.ano_modelo,
-			v.cor,
-			v.tipo_combustivel
-		FROM veiculos v
-		JOIN modelos mo ON v.id_modelos = mo.id_modelos  
-		JOIN marcas m ON mo.id_marcas = m.id_marcas
-		WHERE v.status_veiculo = 'Disponivel'
-		AND ABS(v.preco_venda - $1) <= 5000
-		ORDER BY ABS(v.preco_venda - $1) ASC
-		LIMIT 1
-	`
-
-	row := h.mcpClient.Server.DB.QueryRowContext(ctx, query, targetPrice)
-
-	var marca, modelo, versao, cor, tipoCombustivel string
-	var preco, consumoUrbano, consumoRodoviario, ipva float64
-	var potencia, ano int
-
-	if err := row.Scan(&marca, &modelo, &versao, &preco, &consumoUrbano, &consumoRodoviario, &potencia, &ipva, &ano, &cor, &tipoCombustivel); err != nil {
+	var vehicles []mcp.Vehicle
+	err := h.mcpClient.CallToolJSON(ctx, "get_vehicles_available", map[string]interface{}{
+		"near_price": targetPrice,
+	}, &vehicles)
+	if err != nil || len(vehicles) == 0 {
 		return nil
 	}
 
-	return map[string]interface{}{
-		"marca":              marca,
-		"modelo":             modelo,
-		"versao":             versao,
-		"preco_venda":        preco,
-		"consumo_urbano":     consumoUrbano,
-		"consumo_rodoviario": consumoRodoviario,
-		"potencia_cv":        potencia,
-		"ipva_anual":         ipva,
-		"ano_modelo":         ano,
-		"cor":                cor,
-		"tipo_combustivel":   tipoCombustivel,
+	if math.Abs(vehicles[0].Price-targetPrice) > 5000 {
+		return nil
 	}
+	return &vehicles[0]
 }
 
 func (h *ChatHandler) extractDownPayment(message string) float64 {
@@ -545,7 +487,18 @@ func (h *ChatHandler) extractInstallments(message string) float64 {
 	return 60.0
 }
 
+func (h *ChatHandler) extractAmortizationSystem(message string) string {
+	if regexp.MustCompile(`\bsac\b`).MatchString(message) {
+		return string(finance.SAC)
+	}
+	return string(finance.Price)
+}
+
 func (h *ChatHandler) handleMonthlyPaymentQuestion(ctx context.Context, message string) string {
+	if h.mcpClient == nil {
+		return "❌ Conexão com base de dados indisponível"
+	}
+
 	messageToLower := strings.ToLower(message)
 
 	targetPayment := h.extractTargetPayment(messageToLower)
@@ -555,20 +508,17 @@ func (h *ChatHandler) handleMonthlyPaymentQuestion(ctx context.Context, message
 
 	var vehiclePrice float64
 	var vehicleName string
-
-	if strings.Contains(messageToLower, "chevrolet onix") {
-		vehiclePrice = h.getVehiclePrice(ctx, "Chevrolet", "Onix")
-		vehicleName = "Chevrolet Onix LT 1.0 Turbo"
-	} else if strings.Contains(messageToLower, "fiat argo") {
-		vehiclePrice = h.getVehiclePrice(ctx, "Fiat", "Argo")
-		vehicleName = "Fiat Argo Drive 1.3"
-	} else if strings.Contains(messageToLower, "honda civic") {
-		vehiclePrice = h.getVehiclePrice(ctx, "Honda", "Civic")
-		vehicleName = "Honda Civic LX 2.0 CVT"
-	} else if strings.Contains(messageToLower, "toyota corolla") {
-		vehiclePrice = h.getVehiclePrice(ctx, "Toyota", "Corolla")
-		vehicleName = "Toyota Corolla GLI 1.8 CVT"
-	} else {
+	for _, known := range [][2]string{{"Chevrolet", "Onix"}, {"Fiat", "Argo"}, {"Honda", "Civic"}, {"Toyota", "Corolla"}} {
+		if strings.Contains(messageToLower, strings.ToLower(known[0]+" "+known[1])) {
+			vehicleName = known[0] + " " + known[1]
+			if vehicle := h.getVehicle(ctx, known[0], known[1]); vehicle != nil {
+				vehiclePrice = vehicle.Price
+				vehicleName = fmt.Sprintf("%s %s %s", vehicle.Brand, vehicle.Model, vehicle.Version)
+			}
+			break
+		}
+	}
+	if vehicleName == "" {
 		vehiclePrice = h.getAverageVehiclePrice(ctx)
 		vehicleName = "Veículo da nossa base"
 	}
@@ -577,61 +527,45 @@ func (h *ChatHandler) handleMonthlyPaymentQuestion(ctx context.Context, message
 		return "❌ Veículo não encontrado em nossa base de dados. Consulte 'carro barato' para ver opções disponíveis."
 	}
 
-	query := `
-		SELECT taxa_juros_ano, banco_financiadora
-		FROM financiamentos 
-		WHERE aprovado = true 
-		ORDER BY taxa_juros_ano ASC 
-		LIMIT 1
-	`
-
-	row := h.mcpClient.Server.DB.QueryRowContext(ctx, query)
-	var interestRate float64
-	var bank string
-
-	if err := row.Scan(&interestRate, &bank); err != nil {
-		return "❌ Nenhuma opção de financiamento encontrada na base de dados"
+	// Com entrada informada, busca o menor prazo que cabe na parcela; sem ela,
+	// calcula a entrada necessária no prazo pedido (60x por padrão).
+	args := map[string]interface{}{
+		"vehicle_price": vehiclePrice,
+		"installment":   targetPayment,
 	}
-
-	interestRate = interestRate / 100
-	monthlyRate := interestRate / 12
-	installments := 60.0
-
-	var requiredDownPayment float64
-
-	if monthlyRate > 0 {
-		power := 1.0
-		for i := 0; i < int(installments); i++ {
-			power *= (1 + monthlyRate)
-		}
-		financeAmount := targetPayment * (power - 1) / (monthlyRate * power)
-		requiredDownPayment = vehiclePrice - financeAmount
+	if downPayment := h.extractDownPayment(messageToLower); downPayment > 0 {
+		args["down_payment"] = downPayment
 	} else {
-		financeAmount := targetPayment * installments
-		requiredDownPayment = vehiclePrice - financeAmount
+		args["installments"] = h.extractInstallments(messageToLower)
 	}
 
-	if requiredDownPayment < 0 {
-		requiredDownPayment = 0
+	var solution mcp.FinancingSolution
+	if err := h.mcpClient.CallToolJSON(ctx, "solve_financing", args, &solution); err != nil {
+		return fmt.Sprintf("❌ Não foi possível montar o financiamento: %v", err)
 	}
 
-	financeAmount := vehiclePrice - requiredDownPayment
-	totalAmount := targetPayment * installments
-
 	var response strings.Builder
 	response.WriteString("💡 **Cálculo baseado em nossa base de dados:**\n\n")
 	response.WriteString(fmt.Sprintf("🚘 **Veículo: %s**\n", vehicleName))
-	response.WriteString(fmt.Sprintf("💰 Valor do veículo: R$ %.2f\n", vehiclePrice))
-	response.WriteString(fmt.Sprintf("💸 Parcela desejada: R$ %.2f\n", targetPayment))
-	response.WriteString(fmt.Sprintf("📅 Prazo: %.0f parcelas\n\n", installments))
+	response.WriteString(fmt.Sprintf("💰 Valor do veículo: R$ %.2f\n", solution.VehiclePrice))
+	response.WriteString(fmt.Sprintf("💸 Parcela desejada: R$ %.2f\n\n", targetPayment))
 
 	response.WriteString("💰 **Resultado do Cálculo:**\n")
-	response.WriteString(fmt.Sprintf("💵 **Entrada necessária: R$ %.2f**\n", requiredDownPayment))
-	response.WriteString(fmt.Sprintf("💳 Valor financiado: R$ %.2f\n", financeAmount))
-	response.WriteString(fmt.Sprintf("🏦 **Banco com melhor taxa: %s**\n", bank))
-	response.WriteString(fmt.Sprintf("📊 Taxa: %.2f%% ao ano (%.2f%% ao mês)\n", interestRate*100, monthlyRate*100))
-	response.WriteString(fmt.Sprintf("💵 Total a pagar: R$ %.2f\n", totalAmount))
-	response.WriteString(fmt.Sprintf("💸 Total de juros: R$ %.2f\n", totalAmount-financeAmount))
+	if solution.Solved == "installments" {
+		response.WriteString(fmt.Sprintf("📅 **Menor prazo possível: %d parcelas de R$ %.2f**\n", solution.Installments, solution.Installment))
+		response.WriteString(fmt.Sprintf("💵 Entrada: R$ %.2f\n", solution.DownPayment))
+	} else {
+		response.WriteString(fmt.Sprintf("💵 **Entrada necessária: R$ %.2f**\n", solution.DownPayment))
+		response.WriteString(fmt.Sprintf("📅 Prazo: %d parcelas de R$ %.2f\n", solution.Installments, solution.Installment))
+	}
+	response.WriteString(fmt.Sprintf("💳 Valor financiado: R$ %.2f\n", solution.FinancedAmount))
+	response.WriteString(fmt.Sprintf("🏦 **Banco com melhor taxa: %s**\n", solution.Bank))
+	response.WriteString(fmt.Sprintf("📊 Taxa: %.2f%% ao ano (%.2f%% ao mês)\n", solution.AnnualRate, solution.MonthlyRate))
+	response.WriteString(fmt.Sprintf("💵 Total a pagar: R$ %.2f\n", solution.Total))
+	response.WriteString(fmt.Sprintf("💸 Total de juros: R$ %.2f\n", solution.TotalInterest))
+	if solution.Note != "" {
+		response.WriteString(fmt.Sprintf("📝 %s\n", solution.Note))
+	}
 
 	return response.String()
 }
@@ -651,7 +585,7 @@ func (h *ChatHandler) extractTargetPayment(message string) float64 {
 			valueStr = strings.ReplaceAll(valueStr, ",", ".")
 
 			if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
-				if value >= 200 && value <= 5000 {
+				if value > 0 {
 					return value
 				}
 			}
@@ -660,3 +594,106 @@ func (h *ChatHandler) extractTargetPayment(message string) float64 {
 
 	return 0.0
 }
+
+var (
+	comparisonSubject   = regexp.MustCompile(`compar\S*\s+(?:entre\s+)?(?:os?\s+|as?\s+)?(.+)`)
+	comparisonSeparator = regexp.MustCompile(`\s*(?:,|\s+e\s+|\s+vs\.?\s+|\s+x\s+|\s+com\s+)\s*(?:os?\s+|as?\s+)?`)
+)
+
+// handleComparisonQuestion extrai os veículos de "comparar corolla e civic" e
+// monta a comparação; cada trecho é buscado como modelo ou como marca e modelo.
+// Se algum trecho não for um veículo do estoque ou sobrar menos de dois, devolve
+// "" para que as demais regras tratem a mensagem ("comparar leasing e
+// consórcio", por exemplo).
+func (h *ChatHandler) handleComparisonQuestion(ctx context.Context, message string) string {
+	matches := comparisonSubject.FindStringSubmatch(strings.TrimRight(message, "?!. "))
+	if len(matches) < 2 {
+		return ""
+	}
+
+	var ids []interface{}
+	for _, name := range comparisonSeparator.Split(matches[1], -1) {
+		words := strings.Fields(name)
+		if len(words) == 0 {
+			continue
+		}
+		vehicle := h.getVehicle(ctx, "", strings.Join(words, " "))
+		if vehicle == nil && len(words) > 1 {
+			vehicle = h.getVehicle(ctx, words[0], strings.Join(words[1:], " "))
+		}
+		if vehicle == nil {
+			return ""
+		}
+		ids = append(ids, vehicle.ID)
+	}
+
+	if len(ids) < 2 {
+		return ""
+	}
+	return h.executeCompareVehicles(ctx, map[string]interface{}{"vehicle_ids": ids})
+}
+
+// executeCompareVehicles apresenta compare_vehicles como tabela em markdown,
+// que a interface renderiza como tabela HTML.
+func (h *ChatHandler) executeCompareVehicles(ctx context.Context, params map[string]interface{}) string {
+	if h.mcpClient == nil {
+		return "❌ Conexão com base de dados indisponível"
+	}
+
+	var comparison mcp.VehicleComparison
+	if err := h.mcpClient.CallToolJSON(ctx, "compare_vehicles", params, &comparison); err != nil {
+		return fmt.Sprintf("❌ Erro ao comparar veículos: %v", err)
+	}
+
+	var response strings.Builder
+	response.WriteString("💡 **Comparação baseada em nossa base de dados:**\n\n")
+
+	response.WriteString("| |")
+	for _, v := range comparison.Vehicles {
+		response.WriteString(fmt.Sprintf(" %s %s %s |", v.Brand, v.Model, v.Version))
+	}
+	response.WriteString("\n|---|")
+	response.WriteString(strings.Repeat("---|", len(comparison.Vehicles)))
+	response.WriteString("\n")
+
+	for _, row := range comparison.Rows {
+		response.WriteString(fmt.Sprintf("| %s (%s é melhor) |", row.Dimension, row.Better))
+		for i, value := range row.Values {
+			cell := formatComparisonValue(value, row.Unit)
+			if row.WinnerID != 0 && comparison.Vehicles[i].ID == row.WinnerID {
+				cell = fmt.Sprintf("**%s 🏆**", cell)
+			}
+			response.WriteString(fmt.Sprintf(" %s |", cell))
+		}
+		response.WriteString("\n")
+	}
+
+	response.WriteString("| **Vitórias** |")
+	for _, v := range comparison.Vehicles {
+		response.WriteString(fmt.Sprintf(" **%d** |", v.Wins))
+	}
+	response.WriteString("\n\n❓ Quer simular o financiamento ou o custo total de posse de algum deles?")
+
+	return response.String()
+}
+
+func formatComparisonValue(value *float64, unit string) string {
+	if value == nil {
+		return "—"
+	}
+
+	number := strconv.FormatFloat(*value, 'f', -1, 64)
+	if *value != math.Trunc(*value) {
+		number = fmt.Sprintf("%.1f", *value)
+	}
+
+	switch unit {
+	case "":
+		return number
+	case "R$":
+		return fmt.Sprintf("R$ %.2f", *value)
+	case "%":
+		return number + "%"
+	}
+	return number + " " + unit
+}
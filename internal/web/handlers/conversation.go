@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"mcp-gemini-go/internal/conversation"
+)
+
+const sessionCookieName = "chat_session"
+
+type ConversationHandler struct {
+	store conversation.Store
+}
+
+func NewConversationHandler(store conversation.Store) *ConversationHandler {
+	return &ConversationHandler{store: store}
+}
+
+// HandleList lista as conversas da sessão do cookie.
+func (h *ConversationHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	summaries, err := h.store.List(r.Context(), sessionID(w, r))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Erro ao listar conversas")
+		return
+	}
+	if summaries == nil {
+		summaries = []conversation.Summary{}
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// HandleGet devolve uma conversa da sessão do cookie; conversas de outras
+// sessões respondem como inexistentes.
+func (h *ConversationHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	stored, err := h.store.Get(r.Context(), sessionID(w, r), r.PathValue("id"))
+	if err == conversation.ErrNotFound || err == conversation.ErrForbidden {
+		writeJSONError(w, http.StatusNotFound, "Conversa não encontrada")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Erro ao buscar conversa")
+		return
+	}
+	writeJSON(w, http.StatusOK, stored)
+}
+
+func (h *ConversationHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	err := h.store.Delete(r.Context(), sessionID(w, r), r.PathValue("id"))
+	if err == conversation.ErrNotFound {
+		writeJSONError(w, http.StatusNotFound, "Conversa não encontrada")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Erro ao remover conversa")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sessionID devolve a sessão do cookie, que é dona das conversas, e cria uma
+// nova quando o cookie não existe ou é inválido. O cookie é sempre renovado.
+func sessionID(w http.ResponseWriter, r *http.Request) string {
+	var id string
+	if cookie, err := r.Cookie(sessionCookieName); err == nil && conversation.ValidID(cookie.Value) {
+		id = cookie.Value
+	}
+	if id == "" {
+		id = conversation.NewID()
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id
+}
+
+// conversationID usa o ID enviado na requisição ou, sem ele, a própria sessão
+// como conversa padrão. Devolve false quando o ID enviado é inválido.
+func conversationID(session, requested string) (string, bool) {
+	if requested == "" {
+		return session, true
+	}
+	return requested, conversation.ValidID(requested)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -2,8 +2,55 @@ const messagesDiv = document.getElementById('messages');
 const messageInput = document.getElementById('messageInput');
 const sendButton = document.getElementById('sendButton');
 
+// Tabelas em markdown (cabeçalho, linha |---| e linhas iniciadas por |) viram
+// tabelas HTML; o restante da mensagem segue a formatação de texto.
+function tableCells(line) {
+    return line.trim().replace(/^\|/, '').replace(/\|$/, '').split('|').map(cell => cell.trim());
+}
+
+function renderTable(lines) {
+    let html = '<table class="comparison-table"><thead><tr>';
+    html += tableCells(lines[0]).map(cell => '<th>' + cell + '</th>').join('');
+    html += '</tr></thead><tbody>';
+    for (const line of lines.slice(2)) {
+        html += '<tr>' + tableCells(line).map(cell => '<td>' + cell + '</td>').join('') + '</tr>';
+    }
+    return html + '</tbody></table>';
+}
+
+function formatTables(content) {
+    const lines = content.split('\n');
+    const output = [];
+    for (let i = 0; i < lines.length; i++) {
+        const isTable = lines[i].trim().startsWith('|') &&
+            i + 1 < lines.length && /^\s*\|?\s*:?-{3,}/.test(lines[i + 1]);
+        if (!isTable) {
+            output.push(lines[i]);
+            continue;
+        }
+        let end = i + 2;
+        while (end < lines.length && lines[end].trim().startsWith('|')) {
+            end++;
+        }
+        output.push(renderTable(lines.slice(i, end)));
+        i = end - 1;
+    }
+    return output.join('\n');
+}
+
+// O texto vem do modelo e das ferramentas (versões, campanhas, recalls) e é
+// escapado antes de receber a formatação, que só insere as tags conhecidas.
+function escapeHtml(text) {
+    return text
+        .replace(/&/g, '&amp;')
+        .replace(/</g, '&lt;')
+        .replace(/>/g, '&gt;')
+        .replace(/"/g, '&quot;')
+        .replace(/'/g, '&#39;');
+}
+
 function formatBotMessage(content) {
-    let formatted = content;
+    let formatted = formatTables(escapeHtml(content));
     
     formatted = formatted.replace(/\n/g, '<br>');
     formatted = formatted.replace(/\*\*(.*?)\*\*/g, '<strong>$1</strong>');
@@ -44,6 +91,53 @@ function removeLoadingMessage() {
     }
 }
 
+function parseEvent(block) {
+    let type = 'message';
+    let data = '';
+    for (const line of block.split('\n')) {
+        if (line.startsWith('event: ')) {
+            type = line.slice(7);
+        } else if (line.startsWith('data: ')) {
+            data += line.slice(6);
+        }
+    }
+    return { type, data: data ? JSON.parse(data) : {} };
+}
+
+async function streamChat(message, onEvent) {
+    const response = await fetch('/chat/stream', {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ message: message })
+    });
+
+    if (!response.ok) {
+        const data = await response.json();
+        throw new Error(data.error || response.statusText);
+    }
+
+    const reader = response.body.getReader();
+    const decoder = new TextDecoder();
+    let buffer = '';
+
+    while (true) {
+        const { value, done } = await reader.read();
+        if (done) break;
+
+        buffer += decoder.decode(value, { stream: true });
+        let boundary;
+        while ((boundary = buffer.indexOf('\n\n')) !== -1) {
+            const block = buffer.slice(0, boundary);
+            buffer = buffer.slice(boundary + 2);
+            if (block.trim()) {
+                onEvent(parseEvent(block));
+            }
+        }
+    }
+}
+
 async function sendMessage() {
     const message = messageInput.value.trim();
     if (!message) return;
@@ -55,25 +149,43 @@ async function sendMessage() {
     messageInput.disabled = true;
     
     const loading = addLoadingMessage();
+    let botMessage = null;
+    let content = '';
 
     try {
-        const response = await fetch('/chat', {
-            method: 'POST',
-            headers: {
-                'Content-Type': 'application/json',
-            },
-            body: JSON.stringify({ message: message })
+        await streamChat(message, function(event) {
+            switch (event.type) {
+                case 'token':
+                    if (!botMessage) {
+                        botMessage = document.createElement('div');
+                        botMessage.className = 'message bot-message';
+                        messagesDiv.insertBefore(botMessage, loading);
+                    }
+                    content += event.data.text;
+                    botMessage.innerHTML = formatBotMessage(content);
+                    break;
+                case 'tool_start':
+                    loading.textContent = '🔎 ' + event.data.text;
+                    break;
+                case 'tool_end':
+                    loading.textContent = 'Pensando...';
+                    break;
+                case 'reset':
+                    content = '';
+                    if (botMessage) {
+                        botMessage.innerHTML = '';
+                    }
+                    break;
+                case 'done':
+                    if (!botMessage && event.data.response) {
+                        addMessage(event.data.response);
+                    }
+                    break;
+            }
+            messagesDiv.scrollTop = messagesDiv.scrollHeight;
         });
 
-        const data = await response.json();
-        
         removeLoadingMessage();
-        
-        if (data.error) {
-            addMessage('❌ Erro: ' + data.error);
-        } else {
-            addMessage(data.response);
-        }
     } catch (error) {
         removeLoadingMessage();
         addMessage('❌ Erro de conexão: ' + error.message);
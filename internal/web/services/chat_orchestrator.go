@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"mcp-gemini-go/internal/conversation"
+	"mcp-gemini-go/internal/llm"
+)
+
+const (
+	ChatModeLLM   = "llm"
+	ChatModeRules = "rules"
+)
+
+// EventReset é emitido em Stream quando o texto parcial do modelo deve ser descartado.
+const EventReset = "reset"
+
+type ChatAnswer struct {
+	Content   string
+	Source    string
+	ToolCalls []llm.ToolCall
+}
+
+// RuleFunc é o caminho baseado em regras usado quando o modelo não responde.
+type RuleFunc func(ctx context.Context, message string) string
+
+type ChatOrchestrator struct {
+	llmClient *llm.Client
+	executor  llm.ToolExecutor
+	tools     []map[string]interface{}
+	mode      string
+	history   conversation.Store
+	limits    conversation.Limits
+}
+
+func NewChatOrchestrator(llmClient *llm.Client, executor llm.ToolExecutor, tools []map[string]interface{}, mode string, history conversation.Store, limits conversation.Limits) *ChatOrchestrator {
+	if mode != ChatModeRules {
+		mode = ChatModeLLM
+	}
+	return &ChatOrchestrator{
+		llmClient: llmClient,
+		executor:  executor,
+		tools:     tools,
+		mode:      mode,
+		history:   history,
+		limits:    limits,
+	}
+}
+
+// CanUse informa se a sessão pode conversar em conversationID: a conversa
+// ainda não existe ou pertence à sessão.
+func (o *ChatOrchestrator) CanUse(ctx context.Context, session, conversationID string) bool {
+	_, err := o.history.Get(ctx, session, conversationID)
+	return err != conversation.ErrForbidden
+}
+
+// Answer responde pelo modelo com as ferramentas MCP e recorre às regras quando
+// o modo configurado é "rules" ou o modelo está indisponível. A pergunta e a
+// resposta são gravadas no histórico da conversa.
+func (o *ChatOrchestrator) Answer(ctx context.Context, session, conversationID, message string, rules RuleFunc) *ChatAnswer {
+	return o.respond(ctx, session, conversationID, message, rules, nil)
+}
+
+// Stream é a versão em streaming de Answer: tokens e chamadas de ferramenta
+// são enviados para emit enquanto a resposta é gerada. Se o modelo falhar no
+// meio do caminho, EventReset é emitido antes da resposta por regras.
+func (o *ChatOrchestrator) Stream(ctx context.Context, session, conversationID, message string, rules RuleFunc, emit func(llm.StreamEvent)) *ChatAnswer {
+	return o.respond(ctx, session, conversationID, message, rules, emit)
+}
+
+func (o *ChatOrchestrator) respond(ctx context.Context, session, conversationID, message string, rules RuleFunc, emit func(llm.StreamEvent)) *ChatAnswer {
+	askedAt := time.Now()
+	answer := o.answer(ctx, session, conversationID, message, rules, emit)
+	if ctx.Err() != nil {
+		log.Printf("⚠️ Cliente desconectou da conversa %s antes da resposta terminar", conversationID)
+		return answer
+	}
+
+	err := o.history.Append(ctx, session, conversationID,
+		conversation.Message{Role: conversation.RoleUser, Content: message, CreatedAt: askedAt},
+		conversation.Message{Role: conversation.RoleModel, Content: answer.Content, Source: answer.Source},
+	)
+	if err != nil {
+		log.Printf("⚠️ Erro ao salvar histórico da conversa %s: %v", conversationID, err)
+	}
+
+	return answer
+}
+
+func (o *ChatOrchestrator) answer(ctx context.Context, session, conversationID, message string, rules RuleFunc, emit func(llm.StreamEvent)) *ChatAnswer {
+	if o.mode == ChatModeLLM && o.llmClient != nil {
+		history := o.recentTurns(ctx, session, conversationID)
+
+		var response *llm.ChatResponse
+		var err error
+		if emit == nil {
+			response, err = o.llmClient.CompleteChat(ctx, history, message, o.tools, o.executor)
+		} else {
+			response, err = o.llmClient.StreamChat(ctx, history, message, o.tools, o.executor, emit)
+		}
+
+		if err == nil {
+			log.Printf("💬 Resposta gerada via %s (%d chamadas de ferramenta)", ChatModeLLM, len(response.ToolCalls))
+			return &ChatAnswer{
+				Content:   response.Content,
+				Source:    ChatModeLLM,
+				ToolCalls: response.ToolCalls,
+			}
+		}
+		if ctx.Err() != nil {
+			return &ChatAnswer{Source: ChatModeLLM}
+		}
+		log.Printf("⚠️ Modelo indisponível, usando regras: %v", err)
+		if emit != nil {
+			emit(llm.StreamEvent{Type: EventReset})
+		}
+	}
+
+	log.Printf("💬 Resposta gerada via %s", ChatModeRules)
+	content := rules(ctx, message)
+	if emit != nil {
+		emit(llm.StreamEvent{Type: llm.EventToken, Text: content})
+	}
+	return &ChatAnswer{
+		Content: content,
+		Source:  ChatModeRules,
+	}
+}
+
+func (o *ChatOrchestrator) recentTurns(ctx context.Context, session, conversationID string) []llm.Turn {
+	stored, err := o.history.Get(ctx, session, conversationID)
+	if err != nil {
+		if err != conversation.ErrNotFound {
+			log.Printf("⚠️ Erro ao carregar histórico da conversa %s: %v", conversationID, err)
+		}
+		return nil
+	}
+
+	messages := o.limits.Recent(stored.Messages, time.Now())
+	turns := make([]llm.Turn, 0, len(messages))
+	for _, message := range messages {
+		turns = append(turns, llm.Turn{Role: message.Role, Content: message.Content})
+	}
+	return turns
+}
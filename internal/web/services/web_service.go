@@ -3,16 +3,24 @@ package services
 import (
 	"context"
 	"log"
+	"os"
+	"strconv"
+	"time"
 
+	"mcp-gemini-go/internal/conversation"
+	"mcp-gemini-go/internal/llm"
 	"mcp-gemini-go/internal/mcp"
 
 	"github.com/joho/godotenv"
 )
 
 type WebService struct {
-	MCPClient *mcp.Client
-	MCPServer *mcp.Server
-	Tools     []map[string]interface{}
+	MCPClient    *mcp.Client
+	MCPServer    *mcp.Server
+	LLMClient    *llm.Client
+	History      conversation.Store
+	Orchestrator *ChatOrchestrator
+	Tools        []map[string]interface{}
 }
 
 func NewWebService() (*WebService, error) {
@@ -28,13 +36,15 @@ func NewWebService() (*WebService, error) {
 	if err := mcpServer.Connect(); err != nil {
 		return nil, err
 	}
-	log.Printf("✅ Conectado ao banco PostgreSQL")
 
 	if err := mcpServer.Initialize(); err != nil {
 		return nil, err
 	}
 
-	mcpClient := mcp.NewClientWithServer(mcpServer)
+	mcpClient, err := mcp.NewClientWithServer(ctx, mcpServer)
+	if err != nil {
+		return nil, err
+	}
 	log.Printf("✅ Servidor MCP integrado inicializado")
 
 	tools, err := mcpClient.ListTools(ctx)
@@ -44,15 +54,81 @@ func NewWebService() (*WebService, error) {
 
 	formattedTools := mcpClient.FormatToolsForLLM(tools)
 
+	chatMode := getEnv("CHAT_MODE", ChatModeLLM)
+
+	var llmClient *llm.Client
+	if chatMode != ChatModeRules {
+		llmClient, err = llm.NewClient(ctx, getEnv("GEMINI_MODEL", "gemini-1.5-flash"))
+		if err != nil {
+			log.Printf("Aviso: Gemini indisponível, respostas usarão regras: %v", err)
+		}
+	}
+
+	limits := conversation.Limits{
+		MaxMessages: getEnvInt("HISTORY_MAX_MESSAGES", 20),
+		MaxAge:      getEnvDuration("HISTORY_MAX_AGE", 24*time.Hour),
+	}
+
+	history, err := newHistoryStore(ctx, mcpServer, limits.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	orchestrator := NewChatOrchestrator(llmClient, mcpClient, formattedTools, chatMode, history, limits)
+	log.Printf("✅ Modo de chat: %s", chatMode)
+
 	return &WebService{
-		MCPClient: mcpClient,
-		MCPServer: mcpServer,
-		Tools:     formattedTools,
+		MCPClient:    mcpClient,
+		MCPServer:    mcpServer,
+		LLMClient:    llmClient,
+		History:      history,
+		Orchestrator: orchestrator,
+		Tools:        formattedTools,
 	}, nil
 }
+
 func (ws *WebService) Close() error {
+	if ws.LLMClient != nil {
+		ws.LLMClient.Close()
+	}
+	if ws.MCPClient != nil {
+		ws.MCPClient.Close()
+	}
 	if ws.MCPServer != nil {
 		return ws.MCPServer.Close()
 	}
 	return nil
 }
+
+// newHistoryStore escolhe o armazenamento do histórico. Nos dois, as conversas
+// paradas há mais de maxAge e as menos recentes além do máximo são descartadas.
+func newHistoryStore(ctx context.Context, mcpServer *mcp.Server, maxAge time.Duration) (conversation.Store, error) {
+	maxConversations := getEnvInt("HISTORY_MAX_CONVERSATIONS", 1000)
+	if getEnv("HISTORY_STORE", "memory") == "postgres" {
+		log.Printf("✅ Histórico de conversas no PostgreSQL")
+		return conversation.NewPostgresStore(ctx, mcpServer.DB, maxConversations, maxAge)
+	}
+	log.Printf("✅ Histórico de conversas em memória")
+	return conversation.NewMemoryStore(maxConversations, maxAge), nil
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}